@@ -49,6 +49,11 @@ func NewService(endpoint string, dbname string) (*service, error) {
 	}, nil
 }
 
+func (s *service) Ping(ctx context.Context) error {
+	_, err := s.client.Version(ctx)
+	return err
+}
+
 func (s *service) Collection(ctx context.Context, name string) (driver.Collection, error) {
 	if ok, _ := s.db.CollectionExists(ctx, name); !ok {
 		return s.db.CreateCollection(ctx, name, nil)
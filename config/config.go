@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 )
@@ -11,11 +13,21 @@ type Config struct {
 	Version  string `default:"v1" envconfig:"VERSION"`
 	LogLevel string `default:"debug" envconfig:"LOG_LEVEL"`
 
+	Server   Server
 	Database Database
 	Redis    Redis
 	Kafka    Kafka
 }
 
+type Server struct {
+	ReadTimeout       time.Duration `default:"60s" envconfig:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `default:"10s" envconfig:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `default:"60s" envconfig:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `default:"120s" envconfig:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `default:"1048576" envconfig:"SERVER_MAX_HEADER_BYTES"`
+	MaxBodyBytes      int64         `default:"0" envconfig:"SERVER_MAX_BODY_BYTES"`
+}
+
 type Database struct {
 	Type     string `default:"mysql" envconfig:"DATABASE_TYPE"`
 	Host     string `default:"localhost" envconfig:"DATABASE_HOST"`
@@ -1,8 +1,10 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -23,6 +25,41 @@ type Database interface {
 	Migrate() (int, error)
 }
 
+// Ping checks the connection of d, through its Ping method when it has one,
+// as the Database of NewPG and NewMySQL does, and otherwise through DB:
+//
+//	router.AddReadinessCheck("database", health.CheckerFunc(func(ctx context.Context) error {
+//		return database.Ping(ctx, db)
+//	}))
+func Ping(ctx context.Context, d Database) error {
+	if p, ok := d.(interface{ Ping(context.Context) error }); ok {
+		return p.Ping(ctx)
+	}
+	sqlDB, err := d.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the connection pool of d, through its Close method when it
+// has one, as the Database of NewPG and NewMySQL does, and otherwise
+// through DB. Register it as a shutdown hook:
+//
+//	router.OnAfterShutdown(func(ctx context.Context) error {
+//		return database.Close(db)
+//	})
+func Close(d Database) error {
+	if c, ok := d.(io.Closer); ok {
+		return c.Close()
+	}
+	sqlDB, err := d.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
 func GormLogger() logger.Interface {
 	return logger.New(
 		log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
@@ -113,6 +150,22 @@ func (c *db) Transaction() (tx *gorm.DB, commit func(), close func()) {
 	return tx, commit, close
 }
 
+func (c *db) Close() error {
+	sqlDB, err := c.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func (c *db) Ping(ctx context.Context) error {
+	sqlDB, err := c.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
 func (c *db) Migrate() (int, error) {
 	db, err := c.DB()
 	if err != nil {
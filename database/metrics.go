@@ -0,0 +1,17 @@
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/huylqbk/codesample/metrics"
+)
+
+// RegisterMetrics exposes the connection pool statistics of d in
+// metrics.Registry, labelled with db_name.
+func RegisterMetrics(d Database, name string) error {
+	sqlDB, err := d.DB()
+	if err != nil {
+		return err
+	}
+	return metrics.Registry.Register(collectors.NewDBStatsCollector(sqlDB, name))
+}
@@ -96,6 +96,19 @@ func (s *service) Info(ctx context.Context) (*esapi.Response, error) {
 	return response, nil
 }
 
+func (s *service) Ping(ctx context.Context) error {
+	response, err := s.client.Ping(s.client.Ping.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return errors.New(response.String())
+	}
+	return nil
+}
+
 func (s *service) Index(ctx context.Context, name string, data []byte) (*esapi.Response, error) {
 	response, err := s.client.Indices.Create(
 		name,
@@ -22,6 +22,12 @@ var (
 	ErrorUpdateResource   ErrorMessage = errors.New("update resource error")
 	ErrorAccessResource   ErrorMessage = errors.New("access resource error")
 	ErrorDeleteResource   ErrorMessage = errors.New("delete resource error")
+	ErrorTooManyRequests  ErrorMessage = errors.New("too many requests")
+	ErrorNotFound         ErrorMessage = errors.New("not found")
+	ErrorMethodNotAllowed ErrorMessage = errors.New("method not allowed")
+	ErrorConflict         ErrorMessage = errors.New("request conflicts with another in progress")
+	ErrorRequestTooLarge  ErrorMessage = errors.New("request body too large")
+	ErrorTimeout          ErrorMessage = errors.New("request timed out")
 )
 
 func ToCode(err error) int {
@@ -30,10 +36,70 @@ func ToCode(err error) int {
 	switch cause {
 	case ErrorForbidden:
 		code = http.StatusForbidden
+	case ErrorUnauthorized:
+		code = http.StatusUnauthorized
 	case ErrorInvalidRequest, ErrorIncorrectData, ErrorResourceNotFound:
 		code = http.StatusBadRequest
 	case ErrorServerFailure, ErrorSomethingWrong, ErrorTransaction, ErrorRedisConnection:
 		code = http.StatusInternalServerError
+	case ErrorTooManyRequests:
+		code = http.StatusTooManyRequests
+	case ErrorNotFound:
+		code = http.StatusNotFound
+	case ErrorMethodNotAllowed:
+		code = http.StatusMethodNotAllowed
+	case ErrorConflict:
+		code = http.StatusConflict
+	case ErrorRequestTooLarge:
+		code = http.StatusRequestEntityTooLarge
+	case ErrorTimeout:
+		code = http.StatusServiceUnavailable
 	}
 	return code
 }
+
+// ToType returns a stable identifier for the cause of err, or "" when it is
+// not one of the errors above.
+func ToType(err error) string {
+	switch errors.Cause(err) {
+	case ErrorForbidden:
+		return "forbidden"
+	case ErrorInvalidRequest:
+		return "invalid-request"
+	case ErrorIncorrectData:
+		return "incorrect-data"
+	case ErrorResourceNotFound:
+		return "resource-not-found"
+	case ErrorSomethingWrong:
+		return "something-wrong"
+	case ErrorServerFailure:
+		return "server-failure"
+	case ErrorUnauthorized:
+		return "unauthorized"
+	case ErrorRedisConnection:
+		return "redis-connection"
+	case ErrorTransaction:
+		return "transaction"
+	case ErrorCreateResource:
+		return "create-resource"
+	case ErrorUpdateResource:
+		return "update-resource"
+	case ErrorAccessResource:
+		return "access-resource"
+	case ErrorDeleteResource:
+		return "delete-resource"
+	case ErrorTooManyRequests:
+		return "too-many-requests"
+	case ErrorNotFound:
+		return "not-found"
+	case ErrorMethodNotAllowed:
+		return "method-not-allowed"
+	case ErrorConflict:
+		return "conflict"
+	case ErrorRequestTooLarge:
+		return "request-too-large"
+	case ErrorTimeout:
+		return "timeout"
+	}
+	return ""
+}
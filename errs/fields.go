@@ -0,0 +1,29 @@
+package errs
+
+import "strings"
+
+// FieldError describes one invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors is an invalid request with per-field details. Its cause is
+// ErrorInvalidRequest, so ToCode maps it to 400.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, f := range e {
+		msgs[i] = f.Field + " " + f.Message
+	}
+	return ErrorInvalidRequest.Error() + ": " + strings.Join(msgs, ", ")
+}
+
+func (e FieldErrors) Cause() error {
+	return ErrorInvalidRequest
+}
+
+func (e FieldErrors) Unwrap() error {
+	return ErrorInvalidRequest
+}
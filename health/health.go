@@ -0,0 +1,213 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	defaultTimeout = 3 * time.Second
+)
+
+// Checker reports whether a dependency is usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Pinger is implemented by the minio, elasticsearch and arangodb services
+// and by the Database of database.NewPG and database.NewMySQL; database.Ping
+// checks any other Database.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks a dependency through its Ping method.
+func Ping(p Pinger) Checker {
+	return CheckerFunc(p.Ping)
+}
+
+// Config tunes a single check.
+type Config struct {
+	Timeout  time.Duration // default 3s
+	CacheTTL time.Duration // reuse the last result for this long, 0 disables caching
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the outcome of every check in a Registry.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type check struct {
+	name    string
+	checker Checker
+	config  Config
+
+	mu       sync.Mutex
+	result   Result
+	inflight *flight
+}
+
+// flight is one run of a check shared by every caller that arrives while it
+// is in progress.
+type flight struct {
+	done   chan struct{}
+	result Result
+}
+
+// run returns the cached result or joins the run in progress, starting one
+// if there is none. The checker gets its own timeout rather than the
+// caller's context, so a caller that goes away neither fails the run for the
+// others nor leaves a failure in the cache.
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+	if c.config.CacheTTL > 0 && !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < c.config.CacheTTL {
+		result := c.result
+		c.mu.Unlock()
+		return result
+	}
+	f := c.inflight
+	if f == nil {
+		f = &flight{done: make(chan struct{})}
+		c.inflight = f
+		go c.do(f)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result
+	case <-ctx.Done():
+		return Result{
+			Status:    StatusFail,
+			Error:     ctx.Err().Error(),
+			Duration:  "0s",
+			CheckedAt: time.Now(),
+		}
+	}
+}
+
+func (c *check) do(f *flight) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	f.result = Result{
+		Status:    StatusOK,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		f.result.Status = StatusFail
+		f.result.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	c.result = f.result
+	c.inflight = nil
+	c.mu.Unlock()
+	close(f.done)
+}
+
+// Registry runs named checks concurrently.
+type Registry struct {
+	mu     sync.RWMutex
+	checks []*check
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a named check; a later check with the same name replaces it.
+func (r *Registry) Register(name string, checker Checker, config ...Config) {
+	c := &check{name: name, checker: checker}
+	if len(config) > 0 {
+		c.config = config[0]
+	}
+	if c.config.Timeout <= 0 {
+		c.config.Timeout = defaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.checks {
+		if existing.name == name {
+			r.checks[i] = c
+			return
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+// Check runs every registered check and reports fail if any of them fails.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]*check{}, r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// ServeHTTP writes the report as JSON, with 503 when a check fails.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	report := r.Check(req.Context())
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistryCheck(t *testing.T) {
+	ok := CheckerFunc(func(ctx context.Context) error { return nil })
+	fail := CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+	slow := CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	tests := []struct {
+		name       string
+		register   func(r *Registry)
+		wantStatus string
+		wantCode   int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "no checks",
+			register:   func(r *Registry) {},
+			wantStatus: StatusOK,
+			wantCode:   http.StatusOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name: "all ok",
+			register: func(r *Registry) {
+				r.Register("database", ok)
+				r.Register("minio", ok)
+			},
+			wantStatus: StatusOK,
+			wantCode:   http.StatusOK,
+			wantChecks: map[string]string{"database": StatusOK, "minio": StatusOK},
+		},
+		{
+			name: "one failing",
+			register: func(r *Registry) {
+				r.Register("database", ok)
+				r.Register("elasticsearch", fail)
+			},
+			wantStatus: StatusFail,
+			wantCode:   http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": StatusOK, "elasticsearch": StatusFail},
+		},
+		{
+			name: "timeout",
+			register: func(r *Registry) {
+				r.Register("arangodb", slow, Config{Timeout: 10 * time.Millisecond})
+			},
+			wantStatus: StatusFail,
+			wantCode:   http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"arangodb": StatusFail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.register(r)
+
+			report := r.Check(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("Status = %v, want %v", report.Status, tt.wantStatus)
+			}
+			if len(report.Checks) != len(tt.wantChecks) {
+				t.Errorf("Checks = %v, want %v", report.Checks, tt.wantChecks)
+			}
+			for name, status := range tt.wantChecks {
+				if got := report.Checks[name].Status; got != status {
+					t.Errorf("Checks[%s] = %v, want %v", name, got, status)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRegistryCache(t *testing.T) {
+	calls := 0
+	r := NewRegistry()
+	r.Register("database", CheckerFunc(func(ctx context.Context) error {
+		calls++
+		return nil
+	}), Config{CacheTTL: time.Minute})
+
+	r.Check(context.Background())
+	r.Check(context.Background())
+	if calls != 1 {
+		t.Errorf("checker called %d times, want 1", calls)
+	}
+}
+
+func TestRegistryConcurrentChecks(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	r := NewRegistry()
+	r.Register("database", CheckerFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}))
+
+	reports := make(chan Report, 3)
+	for i := 0; i < 3; i++ {
+		go func() { reports <- r.Check(context.Background()) }()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	for i := 0; i < 3; i++ {
+		if report := <-reports; report.Status != StatusOK {
+			t.Errorf("Status = %v, want %v", report.Status, StatusOK)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("checker called %d times, want 1", n)
+	}
+}
+
+func TestRegistryCancelledCaller(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return ctx.Err()
+	}), Config{CacheTTL: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := r.Check(ctx); report.Status != StatusFail {
+		t.Errorf("cancelled Status = %v, want %v", report.Status, StatusFail)
+	}
+	if report := r.Check(context.Background()); report.Status != StatusOK {
+		t.Errorf("Status after cancelled caller = %v, want %v: %v", report.Status, StatusOK, report.Checks)
+	}
+}
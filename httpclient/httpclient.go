@@ -26,7 +26,7 @@ func Request(url, method string, body map[string]interface{}) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	response, err := client.Do(request)
+	response, err := do(client, request)
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +108,7 @@ func (h *HttpClient) Execute() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	response, err := client.Do(request)
+	response, err := do(client, request)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,39 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/huylqbk/codesample/metrics"
+)
+
+var (
+	clientRequestsTotal = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_requests_total",
+		Help: "Number of outgoing HTTP requests, by host, method and status.",
+	}, []string{"host", "method", "status"})
+
+	clientRequestDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_client_request_duration_seconds",
+		Help:    "Latency of outgoing HTTP requests, by host, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"host", "method", "status"})
+)
+
+// do sends request and records its metrics; transport failures use status "error".
+func do(client *http.Client, request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	response, err := client.Do(request)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(response.StatusCode)
+	}
+	host := request.URL.Host
+	clientRequestsTotal.WithLabelValues(host, request.Method, status).Inc()
+	clientRequestDuration.WithLabelValues(host, request.Method, status).Observe(time.Since(start).Seconds())
+	return response, err
+}
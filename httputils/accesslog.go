@@ -0,0 +1,169 @@
+package httputils
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/huylqbk/codesample/logger"
+)
+
+const redacted = "REDACTED"
+
+// AccessLogConfig configures the access log of AllowLog.
+type AccessLogConfig struct {
+	Logger logger.Logger // defaults to logger.Get(), or a new JSON logger
+	// SampleRate is the share of successful requests logged, such as 0.1;
+	// 4xx and 5xx responses are always logged. Values outside (0, 1) log
+	// every request.
+	SampleRate float64
+	// SkipPaths are not logged, with or without the router prefix; a path
+	// ending in "/" skips everything under it. Defaults to /health, /livez
+	// and /readyz.
+	SkipPaths []string
+	// LogHeaders adds the request headers to each entry.
+	LogHeaders bool
+	// RedactHeaders are logged as REDACTED, defaults to Authorization,
+	// Proxy-Authorization, Cookie and X-Api-Key.
+	RedactHeaders []string
+	// RedactQuery lists query parameters logged as REDACTED, defaults to
+	// token, access_token, api_key, password and secret.
+	RedactQuery []string
+}
+
+var (
+	defaultAccessLogSkipPaths = []string{"/health", "/livez", "/readyz"}
+	defaultRedactHeaders      = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+	defaultRedactQuery        = []string{"token", "access_token", "api_key", "password", "secret"}
+)
+
+type accessLogKey struct{}
+
+// accessEntry collects what the routes learn about a request for its log entry.
+type accessEntry struct {
+	route string
+}
+
+// accessLog writes one structured entry per request through config.Logger
+// with the method, route template, path, status, bytes, latency, client IP,
+// user agent and request ID. It runs ahead of routing so unmatched requests
+// are logged too; the routes report their template through logRoute.
+func accessLog(config AccessLogConfig, prefix string) Middleware {
+	if config.Logger == nil {
+		config.Logger = logger.Get()
+	}
+	if config.Logger == nil {
+		config.Logger = logger.NewLogger()
+	}
+	if config.SkipPaths == nil {
+		config.SkipPaths = defaultAccessLogSkipPaths
+	}
+	if config.RedactHeaders == nil {
+		config.RedactHeaders = defaultRedactHeaders
+	}
+	if config.RedactQuery == nil {
+		config.RedactQuery = defaultRedactQuery
+	}
+	redactHeaders := make(map[string]bool, len(config.RedactHeaders))
+	for _, name := range config.RedactHeaders {
+		redactHeaders[http.CanonicalHeaderKey(name)] = true
+	}
+	redactQuery := make(map[string]bool, len(config.RedactQuery))
+	for _, name := range config.RedactQuery {
+		redactQuery[strings.ToLower(name)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipPath(config.SkipPaths, r.URL.Path) || prefix != "" && skipPath(config.SkipPaths, strings.TrimPrefix(r.URL.Path, prefix)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			entry := &accessEntry{}
+			r = r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry))
+			start := time.Now()
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			latency := time.Since(start)
+
+			status := rw.Status()
+			if status < http.StatusBadRequest && config.SampleRate > 0 && config.SampleRate < 1 && rand.Float64() >= config.SampleRate {
+				return
+			}
+
+			keyvals := []interface{}{
+				"method", r.Method,
+				"route", entry.route,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", rw.bytes,
+				"latency_ms", float64(latency.Microseconds()) / 1000,
+				"client_ip", ClientIP(r),
+				"user_agent", r.UserAgent(),
+				"request_id", GetRequestID(r.Context()),
+			}
+			if r.URL.RawQuery != "" {
+				keyvals = append(keyvals, "query", redactValues(r.URL.Query(), redactQuery))
+			}
+			if config.LogHeaders {
+				keyvals = append(keyvals, "headers", redactHeaderValues(r.Header, redactHeaders))
+			}
+
+			log := config.Logger.WithContext(r.Context())
+			switch {
+			case status >= http.StatusInternalServerError:
+				log.Error("request", keyvals...)
+			case status >= http.StatusBadRequest:
+				log.Warn("request", keyvals...)
+			default:
+				log.Info("request", keyvals...)
+			}
+		})
+	}
+}
+
+// logRoute reports the route template to the access log.
+func logRoute(route string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if entry, ok := r.Context().Value(accessLogKey{}).(*accessEntry); ok {
+				entry.route = route
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func skipPath(skip []string, path string) bool {
+	for _, s := range skip {
+		if path == s || strings.HasSuffix(s, "/") && strings.HasPrefix(path, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValues(values url.Values, redact map[string]bool) string {
+	for name := range values {
+		if redact[strings.ToLower(name)] {
+			values[name] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
+
+func redactHeaderValues(header http.Header, redact map[string]bool) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		if redact[name] {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/huylqbk/codesample/errs"
+	"github.com/huylqbk/codesample/logger"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+// recordingLogger keeps the entries written through it.
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) add(level, msg string, keyvals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *recordingLogger) Debug(msg string, keyvals ...interface{}) { l.add("debug", msg, keyvals) }
+func (l *recordingLogger) Info(msg string, keyvals ...interface{})  { l.add("info", msg, keyvals) }
+func (l *recordingLogger) Warn(msg string, keyvals ...interface{})  { l.add("warn", msg, keyvals) }
+func (l *recordingLogger) Error(msg string, keyvals ...interface{}) { l.add("error", msg, keyvals) }
+func (l *recordingLogger) Fatal(msg string, keyvals ...interface{}) { l.add("fatal", msg, keyvals) }
+func (l *recordingLogger) SetCaller() logger.Logger                 { return l }
+func (l *recordingLogger) SetLevel(level int) logger.Logger         { return l }
+func (l *recordingLogger) Level() int                               { return 0 }
+func (l *recordingLogger) LogFile(path string) logger.Logger        { return l }
+func (l *recordingLogger) WithContext(ctx context.Context) logger.Logger {
+	return l
+}
+
+func (l *recordingLogger) take() []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := l.entries
+	l.entries = nil
+	return entries
+}
+
+func TestAccessLog(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			log := &recordingLogger{}
+			r := newRouter("0").
+				AddPrefix("/api").
+				AllowRequestID().
+				AllowHealthCheck().
+				AllowLog(AccessLogConfig{Logger: log, LogHeaders: true})
+			r.AddPath("/users/{id}", "GET", func(c Context) error {
+				return c.String(http.StatusOK, "user "+c.Param("id"))
+			})
+			r.AddPath("/fail", "GET", func(c Context) error {
+				return errs.ErrorServerFailure
+			})
+			handler, err := r.Handler()
+			if err != nil {
+				t.Fatal(err)
+			}
+			do := func(target string, header ...string) {
+				req := httptest.NewRequest(http.MethodGet, target, nil)
+				for i := 0; i+1 < len(header); i += 2 {
+					req.Header.Set(header[i], header[i+1])
+				}
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			do("/api/users/7?token=secret&q=x", "Authorization", "Bearer secret", "User-Agent", "test-agent", HeaderRequestID, "req-1")
+			entries := log.take()
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			want := map[string]interface{}{
+				"method":     "GET",
+				"route":      "/api/users/{id}",
+				"path":       "/api/users/7",
+				"status":     http.StatusOK,
+				"bytes":      len("user 7"),
+				"user_agent": "test-agent",
+				"request_id": "req-1",
+				"client_ip":  "192.0.2.1",
+			}
+			for k, v := range want {
+				if e.fields[k] != v {
+					t.Errorf("%s = %v, want %v", k, e.fields[k], v)
+				}
+			}
+			if e.level != "info" {
+				t.Errorf("level = %s, want info", e.level)
+			}
+			if q, _ := e.fields["query"].(string); strings.Contains(q, "secret") || !strings.Contains(q, "token="+redacted) {
+				t.Errorf("query = %q, want token redacted", q)
+			}
+			headers, _ := e.fields["headers"].(map[string]string)
+			if headers["Authorization"] != redacted || headers["User-Agent"] != "test-agent" {
+				t.Errorf("headers = %v, want Authorization redacted", headers)
+			}
+
+			do("/api/fail")
+			do("/api/missing")
+			do("/api/health")
+			entries = log.take()
+			if len(entries) != 2 {
+				t.Fatalf("got %d entries, want 2 with /health skipped", len(entries))
+			}
+			if entries[0].level != "error" || entries[0].fields["status"] != http.StatusInternalServerError {
+				t.Errorf("failure logged as %s %v, want error 500", entries[0].level, entries[0].fields["status"])
+			}
+			if entries[1].level != "warn" || entries[1].fields["route"] != "" {
+				t.Errorf("unmatched request logged as %s route %q, want warn without route", entries[1].level, entries[1].fields["route"])
+			}
+		})
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	log := &recordingLogger{}
+	handler := accessLog(AccessLogConfig{Logger: log, SampleRate: 0.000001}, "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	for i := 0; i < 20; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/bad", nil))
+
+	entries := log.take()
+	if len(entries) != 1 || entries[0].fields["status"] != http.StatusBadRequest {
+		t.Errorf("entries = %v, want only the 400", entries)
+	}
+}
+
+func TestSkipPath(t *testing.T) {
+	skip := []string{"/health", "/internal/"}
+	tests := map[string]bool{
+		"/health":       true,
+		"/healthz":      false,
+		"/internal/x/y": true,
+		"/internal":     false,
+	}
+	for path, want := range tests {
+		if got := skipPath(skip, path); got != want {
+			t.Errorf("skipPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/huylqbk/codesample/errs"
+	"github.com/huylqbk/codesample/logger"
+)
+
+var startedAt = time.Now()
+
+// AdminConfig configures the admin endpoints: pprof, runtime stats, build
+// info, graceful shutdown and the log level. With Addr they are served on
+// their own listener, such as "127.0.0.1:6060"; without it they are mounted
+// on the main server under Prefix. Auth is required unless Addr binds a
+// loopback host, since the endpoints can stop the server and dump memory.
+type AdminConfig struct {
+	Addr   string
+	Prefix string        // defaults to "/admin"
+	Auth   Middleware    // guards every admin endpoint
+	Logger logger.Logger // defaults to logger.Get(); the log level endpoint is left out when nil
+}
+
+type runtimeStats struct {
+	GoVersion  string      `json:"go_version"`
+	Goroutines int         `json:"goroutines"`
+	CPUs       int         `json:"cpus"`
+	Uptime     string      `json:"uptime"`
+	Memory     memoryStats `json:"memory"`
+}
+
+type memoryStats struct {
+	Alloc        uint64 `json:"alloc"`
+	TotalAlloc   uint64 `json:"total_alloc"`
+	Sys          uint64 `json:"sys"`
+	HeapAlloc    uint64 `json:"heap_alloc"`
+	HeapInuse    uint64 `json:"heap_inuse"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	NumGC        uint32 `json:"num_gc"`
+	PauseTotalNs uint64 `json:"pause_total_ns"`
+}
+
+type buildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// adminRoutes returns the admin endpoints under config.Prefix.
+func (o *options) adminRoutes(config AdminConfig) routes {
+	if config.Prefix == "" {
+		config.Prefix = "/admin"
+	}
+	if config.Logger == nil {
+		config.Logger = logger.Get()
+	}
+
+	var rs routes
+	add := func(path string, method string, handler HandlerFunc) {
+		var middleware []Middleware
+		if config.Auth != nil {
+			middleware = append(middleware, config.Auth)
+		}
+		rs.add(strings.TrimSuffix(config.Prefix, "/")+path, []string{method}, handler, middleware...)
+	}
+
+	add("/debug/pprof/", "GET", WrapHandler(http.HandlerFunc(pprof.Index)))
+	add("/debug/pprof/{profile}", "GET", func(c Context) error {
+		switch name := c.Param("profile"); name {
+		case "cmdline":
+			pprof.Cmdline(c.Response(), c.Request())
+		case "profile":
+			pprof.Profile(c.Response(), c.Request())
+		case "symbol":
+			pprof.Symbol(c.Response(), c.Request())
+		case "trace":
+			pprof.Trace(c.Response(), c.Request())
+		default:
+			pprof.Handler(name).ServeHTTP(c.Response(), c.Request())
+		}
+		return nil
+	})
+	add("/stats", "GET", func(c Context) error {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		return c.JSON(http.StatusOK, runtimeStats{
+			GoVersion:  runtime.Version(),
+			Goroutines: runtime.NumGoroutine(),
+			CPUs:       runtime.NumCPU(),
+			Uptime:     time.Since(startedAt).Round(time.Second).String(),
+			Memory: memoryStats{
+				Alloc:        m.Alloc,
+				TotalAlloc:   m.TotalAlloc,
+				Sys:          m.Sys,
+				HeapAlloc:    m.HeapAlloc,
+				HeapInuse:    m.HeapInuse,
+				HeapObjects:  m.HeapObjects,
+				NumGC:        m.NumGC,
+				PauseTotalNs: m.PauseTotalNs,
+			},
+		})
+	})
+	add("/build", "GET", func(c Context) error {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return errs.ErrorResourceNotFound
+		}
+		settings := make(map[string]string, len(info.Settings))
+		for _, s := range info.Settings {
+			settings[s.Key] = s.Value
+		}
+		return c.JSON(http.StatusOK, buildInfo{
+			GoVersion: info.GoVersion,
+			Path:      info.Main.Path,
+			Version:   info.Main.Version,
+			Settings:  settings,
+		})
+	})
+	add("/shutdown", "POST", func(c Context) error {
+		if !o.stop() {
+			return errs.ErrorSomethingWrong
+		}
+		return c.NoContent(http.StatusAccepted)
+	})
+	if l := config.Logger; l != nil {
+		add("/log/level", "GET", func(c Context) error {
+			return c.JSON(http.StatusOK, logLevel{Level: logger.LevelName(l.Level())})
+		})
+		add("/log/level", "PUT", func(c Context) error {
+			var req logLevel
+			if err := c.Bind(&req); err != nil {
+				return err
+			}
+			level, err := logger.ParseLevel(req.Level)
+			if err != nil {
+				return errs.FieldErrors{{Field: "level", Message: "must be a log level such as debug or info"}}
+			}
+			l.SetLevel(level)
+			log.Println("admin: log level set to", logger.LevelName(level))
+			return c.JSON(http.StatusOK, logLevel{Level: logger.LevelName(level)})
+		})
+	}
+	return rs
+}
+
+// checkAuth requires Auth unless the admin endpoints only listen on loopback.
+func (c AdminConfig) checkAuth() error {
+	switch {
+	case c.Auth != nil || loopback(c.Addr):
+		return nil
+	case c.Addr == "":
+		return errors.New("admin: Auth is required to serve admin endpoints on the main port")
+	default:
+		return errors.New("admin: Auth is required unless Addr binds a loopback host such as 127.0.0.1")
+	}
+}
+
+// loopback reports whether addr only accepts local connections.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// addAdminRoutes mounts the admin endpoints on the main server.
+func (o *options) addAdminRoutes(rs *routes) {
+	*rs = append(*rs, o.adminRoutes(*o.admin)...)
+}
+
+// adminServer returns the server for admin endpoints with their own
+// address, or nil.
+func (o *options) adminServer() *http.Server {
+	if o.admin == nil || o.admin.Addr == "" {
+		return nil
+	}
+	router := chi.NewRouter()
+	router.NotFound(notFoundHandler)
+	router.MethodNotAllowed(methodNotAllowedHandler)
+	router.Use(Recovery)
+	for _, rt := range o.adminRoutes(*o.admin) {
+		router.Method(rt.Method, rt.Path, rt.handler(chiParams))
+	}
+	return &http.Server{Addr: o.admin.Addr, Handler: router}
+}
+
+// serve runs server until ctx is done, along with the admin server when it
+// has its own address.
+func (o *options) serve(ctx context.Context, server *http.Server) error {
+	admin := o.adminServer()
+	if admin == nil {
+		return o.listen(ctx, server)
+	}
+
+	ln, err := net.Listen("tcp", admin.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		log.Println("Admin server started on: " + ln.Addr().String())
+		if err := admin.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Println("admin:", err)
+		}
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		admin.Shutdown(ctx)
+	}()
+	return o.listen(ctx, server)
+}
@@ -0,0 +1,128 @@
+package httputils
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huylqbk/codesample/errs"
+	"github.com/huylqbk/codesample/logger"
+)
+
+func adminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin" {
+			WriteError(w, r, errs.ErrorUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestAllowAdmin(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			log := logger.NewLogger()
+			r := newRouter("0").AllowAdmin(AdminConfig{Auth: adminToken, Logger: log})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			done := make(chan error, 1)
+			go func() { done <- r.Run(ctx) }()
+			for i := 0; i < 100 && r.Addr() == ""; i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			do := func(method, path, token, body string) int {
+				req, _ := http.NewRequest(method, "http://"+r.Addr()+path, strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				if token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				return resp.StatusCode
+			}
+
+			if code := do("GET", "/admin/stats", "", ""); code != http.StatusUnauthorized {
+				t.Errorf("stats without auth = %d, want 401", code)
+			}
+			for _, path := range []string{"/admin/stats", "/admin/build", "/admin/debug/pprof/", "/admin/debug/pprof/heap?debug=1", "/admin/log/level"} {
+				if code := do("GET", path, "admin", ""); code != http.StatusOK {
+					t.Errorf("GET %s = %d, want 200", path, code)
+				}
+			}
+			if code := do("PUT", "/admin/log/level", "admin", `{"level":"verbose"}`); code != http.StatusBadRequest {
+				t.Errorf("PUT invalid level = %d, want 400", code)
+			}
+			if code := do("PUT", "/admin/log/level", "admin", `{"level":"debug"}`); code != http.StatusOK || logger.LevelName(log.Level()) != "debug" {
+				t.Errorf("PUT level = %d, level %s, want 200 and debug", code, logger.LevelName(log.Level()))
+			}
+			if code := do("POST", "/quit", "", ""); code != http.StatusNotFound {
+				t.Errorf("POST /quit = %d, want 404", code)
+			}
+
+			if code := do("POST", "/admin/shutdown", "admin", ""); code != http.StatusAccepted {
+				t.Errorf("shutdown = %d, want 202", code)
+			}
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Run() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("server did not shut down")
+			}
+		})
+	}
+}
+
+func TestAllowAdminSeparateAddr(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminAddr := ln.Addr().String()
+	ln.Close()
+
+	r := NewEchoRouter("0").AllowAdmin(AdminConfig{Addr: adminAddr})
+	addr, stop := start(t, r)
+	defer stop()
+
+	resp, err := http.Get("http://" + adminAddr + "/admin/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("admin stats = %d, want 200", resp.StatusCode)
+	}
+
+	resp, err = http.Get("http://" + addr + "/admin/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("stats on main port = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestAllowAdminRequiresAuth(t *testing.T) {
+	for _, addr := range []string{"", ":6060", "0.0.0.0:6060", "10.0.0.1:6060"} {
+		r := NewMuxRouter("0").AllowAdmin(AdminConfig{Addr: addr})
+		if err := r.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "Auth") {
+			t.Errorf("Addr %q: Run() error = %v, want Auth required", addr, err)
+		}
+	}
+	for _, addr := range []string{"127.0.0.1:6060", "[::1]:6060", "localhost:6060"} {
+		if !loopback(addr) {
+			t.Errorf("loopback(%q) = false, want true", addr)
+		}
+	}
+}
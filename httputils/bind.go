@@ -0,0 +1,189 @@
+package httputils
+
+import (
+	"encoding"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/huylqbk/codesample/errs"
+	"github.com/huylqbk/codesample/validation"
+)
+
+const maxMultipartMemory = 32 << 20
+
+// bind decodes r into v, a pointer to a struct, and validates it. The body
+// is read as JSON or, for form content types, from `form` tags; `query` and
+// `param` tags then fill fields from the query string and path parameters.
+func bind(r *http.Request, params func(name string) string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("httputils: bind target must be a pointer to a struct")
+	}
+
+	if err := bindBody(r, v); err != nil {
+		return err
+	}
+
+	var fields errs.FieldErrors
+	query := r.URL.Query()
+	bindValues(rv.Elem(), "query", func(name string) []string {
+		return query[name]
+	}, &fields)
+	if params != nil {
+		bindValues(rv.Elem(), "param", func(name string) []string {
+			if s := params(name); s != "" {
+				return []string{s}
+			}
+			return nil
+		}, &fields)
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+	return validation.Struct(v)
+}
+
+func bindBody(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		var err error
+		if mediaType == "multipart/form-data" {
+			err = r.ParseMultipartForm(maxMultipartMemory)
+		} else {
+			err = r.ParseForm()
+		}
+		if err != nil {
+			return bodyError(err)
+		}
+		var fields errs.FieldErrors
+		bindValues(reflect.ValueOf(v).Elem(), "form", func(name string) []string {
+			return r.PostForm[name]
+		}, &fields)
+		if len(fields) > 0 {
+			return fields
+		}
+		return nil
+	}
+
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return errs.FieldErrors{{Field: typeErr.Field, Message: "must be " + typeErr.Type.String()}}
+		}
+		return bodyError(err)
+	}
+	return nil
+}
+
+// bodyError reports a body that could not be read or parsed; one over the
+// MaxBodySize limit keeps its errs.ErrorRequestTooLarge.
+func bodyError(err error) error {
+	if errors.Is(err, errs.ErrorRequestTooLarge) {
+		return errs.ErrorRequestTooLarge
+	}
+	return errors.Wrap(errs.ErrorInvalidRequest, err.Error())
+}
+
+// bindValues sets each field tagged with key from lookup, recursing into
+// embedded structs. Conversion failures are added to fields.
+func bindValues(v reflect.Value, key string, lookup func(name string) []string, fields *errs.FieldErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			bindValues(v.Field(i), key, lookup, fields)
+			continue
+		}
+		name := strings.Split(f.Tag.Get(key), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		values := lookup(name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := setValue(v.Field(i), values); err != nil {
+			*fields = append(*fields, errs.FieldError{Field: name, Message: err.Error()})
+		}
+	}
+}
+
+func setValue(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setScalar(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setScalar(v, values[0])
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(s)); err != nil {
+				return errors.New("must be a valid " + v.Type().String())
+			}
+			return nil
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		return errors.New("has an unsupported type " + v.Type().String())
+	}
+	return nil
+}
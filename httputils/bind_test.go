@@ -0,0 +1,77 @@
+package httputils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type bindRequest struct {
+	ID      int      `param:"id"`
+	Verbose bool     `query:"verbose"`
+	Tags    []string `query:"tag"`
+	Name    string   `json:"name" form:"name" validate:"required,min=2"`
+	Email   string   `json:"email" form:"email" validate:"email"`
+}
+
+func TestBind(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").AddPath("/users/{id}", "POST", func(c Context) error {
+				var req bindRequest
+				if err := c.Bind(&req); err != nil {
+					return err
+				}
+				return c.JSON(http.StatusOK, req)
+			})
+			addr, stop := start(t, r)
+			defer stop()
+
+			tests := []struct {
+				name        string
+				path        string
+				contentType string
+				body        string
+				wantStatus  int
+				wantFields  []string
+			}{
+				{name: "json", path: "/users/7?verbose=true&tag=a&tag=b", contentType: "application/json", body: `{"name":"ann","email":"ann@example.com"}`, wantStatus: http.StatusOK},
+				{name: "form", path: "/users/7?verbose=true&tag=a&tag=b", contentType: "application/x-www-form-urlencoded", body: url.Values{"name": {"ann"}, "email": {"ann@example.com"}}.Encode(), wantStatus: http.StatusOK},
+				{name: "invalid fields", path: "/users/7", contentType: "application/json", body: `{"name":"a","email":"nope"}`, wantStatus: http.StatusBadRequest, wantFields: []string{"name", "email"}},
+				{name: "bad path param", path: "/users/x", contentType: "application/json", body: `{"name":"ann"}`, wantStatus: http.StatusBadRequest, wantFields: []string{"id"}},
+				{name: "wrong json type", path: "/users/7", contentType: "application/json", body: `{"name":1}`, wantStatus: http.StatusBadRequest, wantFields: []string{"name"}},
+				{name: "malformed json", path: "/users/7", contentType: "application/json", body: `{`, wantStatus: http.StatusBadRequest},
+			}
+			for _, tt := range tests {
+				resp, err := http.Post("http://"+addr+tt.path, tt.contentType, strings.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.wantStatus)
+				}
+				if tt.wantStatus == http.StatusOK {
+					var got bindRequest
+					json.NewDecoder(resp.Body).Decode(&got)
+					if got.ID != 7 || !got.Verbose || len(got.Tags) != 2 || got.Name != "ann" {
+						t.Errorf("%s: bound %+v", tt.name, got)
+					}
+				} else {
+					var p Problem
+					json.NewDecoder(resp.Body).Decode(&p)
+					if len(p.Errors) != len(tt.wantFields) {
+						t.Errorf("%s: errors = %v, want fields %v", tt.name, p.Errors, tt.wantFields)
+					}
+					for i, f := range p.Errors {
+						if i < len(tt.wantFields) && f.Field != tt.wantFields[i] {
+							t.Errorf("%s: errors[%d] = %q, want %q", tt.name, i, f.Field, tt.wantFields[i])
+						}
+					}
+				}
+				resp.Body.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package httputils
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	HeaderCache = "X-Cache"
+
+	defaultCacheTTL = time.Minute
+)
+
+// CacheConfig configures the Cache middleware.
+type CacheConfig struct {
+	// Store keeps GET responses; without one, Cache only adds ETags and
+	// answers conditional requests.
+	Store *ResponseCache
+	// TTL applies to responses whose Cache-Control allows storing but sets
+	// no max-age or s-maxage, such as "public"; defaults to 1 minute.
+	TTL      time.Duration
+	WeakETag bool // W/"..." ETags, for bodies that are equivalent rather than identical
+	// Vary lists the request headers that select between stored responses,
+	// defaults to Accept and Accept-Encoding.
+	Vary []string
+}
+
+// Cache adds an ETag to successful GET responses that lack one and answers
+// If-None-Match and If-Modified-Since with 304. With a Store it also serves
+// repeated GETs from memory, following the Cache-Control of the request and
+// response and the response's Vary, and drops the stored responses of a
+// path once a POST, PUT, PATCH or DELETE on it or on one of its items
+// succeeds; add it to the Router or a Group so those requests pass through
+// it. Responses are buffered, so event streams and WebSocket upgrades are
+// passed through untouched.
+//
+// Only responses with a Cache-Control that allows storing are kept. Requests
+// with an Authorization or Cookie header are neither served from nor stored
+// in the Store. A stored response is served before the middleware added
+// after Cache runs, so add Cache after any middleware that authorizes
+// requests by other means. Headers set by middleware outside Cache, such as
+// those of Cors, are not stored and so stay specific to each request.
+func Cache(config ...CacheConfig) Middleware {
+	var c CacheConfig
+	if len(config) > 0 {
+		c = config[0]
+	}
+	if c.TTL <= 0 {
+		c.TTL = defaultCacheTTL
+	}
+	if c.Vary == nil {
+		c.Vary = []string{"Accept", "Accept-Encoding"}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				if c.Store == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				rw := newResponseWriter(w)
+				next.ServeHTTP(rw, r)
+				if rw.Status() < http.StatusBadRequest {
+					c.Store.Invalidate(r.URL.Path)
+					// the collection lists the changed item
+					c.Store.Invalidate(path.Dir(r.URL.Path))
+				}
+				return
+			}
+			if acceptsEventStream(r) || websocket.IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			store := c.Store
+			if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+				// the response may belong to this client only
+				store = nil
+			}
+			requestCC := parseCacheControl(r.Header.Get("Cache-Control"))
+			key := cacheKey(r, c.Vary)
+			if store != nil && !requestCC.has("no-cache") && r.Header.Get("Pragma") != "no-cache" {
+				if e, ok := store.get(key); ok && e.matches(r) {
+					header := w.Header()
+					for name, values := range e.header {
+						if name == "Vary" {
+							header[name] = append(header[name], values...)
+							continue
+						}
+						header[name] = values
+					}
+					header.Set("Age", strconv.Itoa(int(store.now().Sub(e.stored).Seconds())))
+					header.Set(HeaderCache, "HIT")
+					writeConditional(w, r, e.status, e.body)
+					return
+				}
+			}
+
+			outer := w.Header().Clone()
+			cw := &cacheWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(cw, r)
+
+			header := w.Header()
+			// HEAD has no body to tag or store
+			if cw.status == http.StatusOK && r.Method == http.MethodGet {
+				if header.Get("ETag") == "" {
+					header.Set("ETag", etag(cw.body.Bytes(), c.WeakETag))
+				}
+				if store != nil {
+					header.Set(HeaderCache, "MISS")
+					if ttl, ok := cacheTTL(requestCC, header, c.TTL); ok {
+						stored := storedHeader(header, outer)
+						store.set(&cacheEntry{
+							key:    key,
+							path:   r.URL.Path,
+							status: cw.status,
+							header: stored,
+							vary:   varyValues(r, stored),
+							body:   cw.body.Bytes(),
+							stored: store.now(),
+							ttl:    ttl,
+						})
+					}
+				}
+			}
+			writeConditional(w, r, cw.status, cw.body.Bytes())
+		})
+	}
+}
+
+// writeConditional writes the response, or 304 when the request's
+// validators match it.
+func writeConditional(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	header := w.Header()
+	if status == http.StatusOK && notModified(r, header) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since without it, as
+// RFC 7232 describes for GET and HEAD.
+func notModified(r *http.Request, header http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		current := header.Get("ETag")
+		if current == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || weakMatch(tag, current) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(ims)
+}
+
+// weakMatch compares ETags ignoring the weak indicator.
+func weakMatch(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+func etag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+func cacheKey(r *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(r.URL.RequestURI())
+	for _, name := range vary {
+		b.WriteString("\n" + name + ": " + r.Header.Get(name))
+	}
+	return b.String()
+}
+
+// storedHeader returns the headers of a response to store: those the handler
+// set, without the ones of outer middleware, which are set again for every
+// request, and without CORS and replaySkipHeaders.
+func storedHeader(header, outer http.Header) http.Header {
+	stored := http.Header{}
+	for name, values := range header {
+		if replaySkipHeaders[name] || name == HeaderCache || strings.HasPrefix(name, "Access-Control-") {
+			continue
+		}
+	nextValue:
+		for _, v := range values {
+			for _, o := range outer[name] {
+				if o == v {
+					continue nextValue
+				}
+			}
+			stored.Add(name, v)
+		}
+	}
+	return stored.Clone()
+}
+
+// varyValues records the request headers named by the response's Vary.
+func varyValues(r *http.Request, header http.Header) map[string]string {
+	var vary map[string]string
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(map[string]string)
+			}
+			vary[name] = strings.Join(r.Header.Values(name), ", ")
+		}
+	}
+	return vary
+}
+
+// cacheTTL returns how long the response may be stored, and false when it
+// must not be.
+func cacheTTL(requestCC cacheControl, header http.Header, def time.Duration) (time.Duration, bool) {
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	switch {
+	case len(cc) == 0,
+		requestCC.has("no-store"),
+		cc.has("no-store"), cc.has("no-cache"), cc.has("private"),
+		header.Get("Vary") == "*",
+		header.Get("Set-Cookie") != "":
+		return 0, false
+	}
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds <= 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return def, true
+}
+
+// cacheControl maps Cache-Control directives to their values.
+type cacheControl map[string]string
+
+func parseCacheControl(s string) cacheControl {
+	cc := cacheControl{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// cacheWriter buffers a response; headers go straight to the real writer.
+type cacheWriter struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = code
+	}
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.body.Write(b)
+}
+
+// ResponseCache is a bounded in-memory LRU of responses for Cache. It is
+// safe for concurrent use and may be shared by several routes.
+type ResponseCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // most recently used first
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+type cacheEntry struct {
+	key    string
+	path   string
+	status int
+	header http.Header
+	vary   map[string]string // request headers named by the response's Vary
+	body   []byte
+	stored time.Time
+	ttl    time.Duration
+}
+
+// matches reports whether r selects e under the response's Vary.
+func (e *cacheEntry) matches(r *http.Request) bool {
+	for name, value := range e.vary {
+		if strings.Join(r.Header.Values(name), ", ") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// NewResponseCache keeps up to capacity responses, dropping the least
+// recently used first.
+func NewResponseCache(capacity int) *ResponseCache {
+	if capacity <= 0 {
+		panic("httputils: response cache needs a positive capacity")
+	}
+	return &ResponseCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (c *ResponseCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if c.now().Sub(e.stored) >= e.ttl {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e, true
+}
+
+func (c *ResponseCache) set(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.order.PushFront(e)
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *ResponseCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+// Invalidate drops the stored responses of path, whatever their query.
+func (c *ResponseCache) Invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, el := range c.entries {
+		if el.Value.(*cacheEntry).path == path {
+			c.remove(el)
+		}
+	}
+}
+
+// Purge drops every stored response.
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// Len returns the number of stored responses, including expired ones not
+// yet dropped.
+func (c *ResponseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
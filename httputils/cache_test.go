@@ -0,0 +1,201 @@
+package httputils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheConditional(t *testing.T) {
+	modified := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := Cache()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"catalogue"}`)
+	}))
+	tag := etag([]byte(`{"name":"catalogue"}`), false)
+
+	tests := []struct {
+		name       string
+		header     map[string]string
+		wantStatus int
+	}{
+		{name: "no validators", wantStatus: http.StatusOK},
+		{name: "matching etag", header: map[string]string{"If-None-Match": tag}, wantStatus: http.StatusNotModified},
+		{name: "weak match", header: map[string]string{"If-None-Match": `"other", W/` + tag}, wantStatus: http.StatusNotModified},
+		{name: "any", header: map[string]string{"If-None-Match": "*"}, wantStatus: http.StatusNotModified},
+		{name: "other etag", header: map[string]string{"If-None-Match": `"other"`}, wantStatus: http.StatusOK},
+		{name: "not modified since", header: map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)}, wantStatus: http.StatusNotModified},
+		{name: "modified since", header: map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}, wantStatus: http.StatusOK},
+		{
+			name:       "etag wins over date",
+			header:     map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/catalogue", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("ETag"); got != tag {
+				t.Errorf("ETag = %s, want %s", got, tag)
+			}
+			if tt.wantStatus == http.StatusNotModified && rec.Body.Len() > 0 {
+				t.Errorf("304 has a body: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	var calls int32
+	store := NewResponseCache(10)
+	handler := Cache(CacheConfig{Store: store, WeakETag: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		switch r.URL.Path {
+		case "/uncached":
+		case "/public":
+			w.Header().Set("Cache-Control", "public")
+		case "/private":
+			w.Header().Set("Cache-Control", "private")
+		case "/short":
+			w.Header().Set("Cache-Control", "max-age=1")
+		case "/language":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	do := func(method, target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do("GET", "/products?page=1")
+	hit := do("GET", "/products?page=1")
+	if first.Header().Get(HeaderCache) != "MISS" || hit.Header().Get(HeaderCache) != "HIT" {
+		t.Errorf("%s = %q then %q, want MISS then HIT", HeaderCache, first.Header().Get(HeaderCache), hit.Header().Get(HeaderCache))
+	}
+	if hit.Body.String() != "response 1" || hit.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("hit = %q %s, want the first response", hit.Body.String(), hit.Header().Get("ETag"))
+	}
+	if rec := do("GET", "/products?page=1", "If-None-Match", first.Header().Get("ETag")); rec.Code != http.StatusNotModified {
+		t.Errorf("conditional hit: status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+	if rec := do("GET", "/products?page=1", "Cache-Control", "no-cache"); rec.Body.String() != "response 2" {
+		t.Errorf("no-cache request = %q, want a fresh response", rec.Body.String())
+	}
+	if rec := do("GET", "/products?page=1", "Accept", "text/csv"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("other Accept was served from the cache")
+	}
+
+	// a write to an item drops the collection
+	do("PUT", "/products/1")
+	if rec := do("GET", "/products?page=1"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("collection still cached after a PUT on an item")
+	}
+
+	do("GET", "/private")
+	if rec := do("GET", "/private"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("private response was cached")
+	}
+	do("GET", "/uncached")
+	if rec := do("GET", "/uncached"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("response without Cache-Control was cached")
+	}
+	// credentialed requests bypass the store both ways, even for public responses
+	do("GET", "/public", "Authorization", "Bearer x")
+	if rec := do("GET", "/public"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("authorized response was shared")
+	}
+	do("GET", "/products/3", "Cookie", "session=x")
+	if rec := do("GET", "/products/3"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("response to a cookie was shared")
+	}
+	if rec := do("GET", "/public", "Cookie", "session=x"); rec.Header().Get(HeaderCache) != "" || rec.Body.String() == "" {
+		t.Errorf("request with a cookie: %s = %q, want the handler's response", HeaderCache, rec.Header().Get(HeaderCache))
+	}
+	do("GET", "/language", "Accept-Language", "en")
+	if rec := do("GET", "/language", "Accept-Language", "vi"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("response was served to another Accept-Language despite its Vary")
+	}
+	if rec := do("GET", "/language", "Accept-Language", "vi"); rec.Header().Get(HeaderCache) != "HIT" || rec.Header().Get("Vary") != "Accept-Language" {
+		t.Errorf("same Accept-Language: %s Vary %q, want HIT", rec.Header().Get(HeaderCache), rec.Header().Get("Vary"))
+	}
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	do("GET", "/short")
+	now = now.Add(2 * time.Second)
+	if rec := do("GET", "/short"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("response outlived its max-age")
+	}
+}
+
+func TestCacheCors(t *testing.T) {
+	cors := Cors(CorsConfig{AllowOrigins: []string{"https://a.example", "https://b.example"}})
+	handler := cors(Cache(CacheConfig{Store: NewResponseCache(10)})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(w, "catalogue")
+	})))
+
+	for i, origin := range []string{"https://a.example", "https://b.example"} {
+		req := httptest.NewRequest(http.MethodGet, "/catalogue", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q (%s)", origin, got, rec.Header().Get(HeaderCache))
+		}
+		if got := rec.Header().Values("Vary"); len(got) != 1 || got[0] != "Origin" {
+			t.Errorf("%s: Vary = %v, want [Origin]", origin, got)
+		}
+		if i > 0 && rec.Header().Get(HeaderCache) != "HIT" {
+			t.Errorf("%s: %s = %q, want HIT", origin, HeaderCache, rec.Header().Get(HeaderCache))
+		}
+	}
+}
+
+func TestResponseCacheLRU(t *testing.T) {
+	c := NewResponseCache(2)
+	for _, key := range []string{"a", "b"} {
+		c.set(&cacheEntry{key: key, path: "/" + key, stored: time.Now(), ttl: time.Minute})
+	}
+	c.get("a") // b is now the least recently used
+	c.set(&cacheEntry{key: "c", path: "/c", stored: time.Now(), ttl: time.Minute})
+
+	if _, ok := c.get("b"); ok {
+		t.Error("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	c.Invalidate("/a")
+	if c.Len() != 1 {
+		t.Errorf("Len after Invalidate = %d, want 1", c.Len())
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len after Purge = %d, want 0", c.Len())
+	}
+}
@@ -1,42 +1,29 @@
 package httputils
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-)
 
-type ChiHandle struct {
-	Method  string
-	Handler http.HandlerFunc
-}
+	"github.com/huylqbk/codesample/health"
+)
 
 type ChiRouter struct {
-	port        string
-	router      *chi.Mux
-	prefix      string
-	middleware  []func(next http.Handler) http.Handler
-	handlers    map[string]ChiHandle
-	healthCheck bool
-	logRequest  bool
-	cors        bool
-	recovery    bool
+	port       string
+	router     *chi.Mux
+	middleware []Middleware
+	options
 }
 
 func NewChiRouter(port string) Router {
 	return &ChiRouter{
 		port:       port,
 		router:     chi.NewRouter(),
-		middleware: make([]func(next http.Handler) http.Handler, 0),
-		handlers:   make(map[string]ChiHandle, 0),
+		middleware: make([]Middleware, 0),
 	}
 }
 
@@ -46,6 +33,7 @@ func (r *ChiRouter) Default() {
 		AllowHealthCheck().
 		AllowLog().
 		AllowRecovery().
+		AllowRequestID().
 		ServeHTTP()
 }
 
@@ -59,8 +47,12 @@ func (r *ChiRouter) AllowRecovery() Router {
 	return r
 }
 
-func (r *ChiRouter) AllowLog() Router {
-	r.logRequest = true
+func (r *ChiRouter) AllowLog(config ...AccessLogConfig) Router {
+	var accessLog AccessLogConfig
+	if len(config) > 0 {
+		accessLog = config[0]
+	}
+	r.accessLog = &accessLog
 	return r
 }
 
@@ -69,95 +61,158 @@ func (r *ChiRouter) AllowHealthCheck() Router {
 	return r
 }
 
-func (r *ChiRouter) AllowCors() Router {
-	r.cors = true
+func (r *ChiRouter) AllowCors(config ...CorsConfig) Router {
+	cors := DefaultCorsConfig
+	if len(config) > 0 {
+		cors = config[0]
+	}
+	r.cors = &cors
 	return r
 }
 
-func (r *ChiRouter) ServeHTTP() {
+func (r *ChiRouter) AllowMetrics() Router {
+	r.metrics = true
+	return r
+}
 
-	// middleware
-	if r.logRequest {
-		r.AddMiddleware(middleware.Logger)
-	}
-	if r.cors {
-		r.AddMiddleware(r.accessControlMiddleware)
-	}
-	if r.recovery {
-		r.AddMiddleware(middleware.Recoverer)
-	}
+func (r *ChiRouter) AllowRequestID() Router {
+	r.requestID = true
+	return r
+}
 
-	for _, h := range r.middleware {
-		r.router.Use(h)
+func (r *ChiRouter) AllowOpenAPI(config ...OpenAPIConfig) Router {
+	openapi := DefaultOpenAPIConfig
+	if len(config) > 0 {
+		openapi = config[0]
 	}
+	r.openapi = &openapi
+	return r
+}
 
-	// handler
-	if r.healthCheck {
-		r.AddPath("/health", "GET", r.healthCheckHandler)
-	}
+func (r *ChiRouter) AllowAdmin(config AdminConfig) Router {
+	r.admin = &config
+	return r
+}
 
-	r.router.Route(r.prefix, func(router chi.Router) {
-		for p, h := range r.handlers {
-			log.Printf("api: %s, method: %s", p, h.Method)
-			router.Method(h.Method, p, h.Handler)
-		}
-	})
-
-	// server
-	server := http.Server{
-		Addr:         ":" + r.port,
-		Handler:      r.router,
-		WriteTimeout: 60 * time.Second,
-		ReadTimeout:  60 * time.Second,
-	}
-	errs := make(chan error)
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		errs <- fmt.Errorf("%s", <-c)
-	}()
+func (r *ChiRouter) Describe(path, method string, op Operation) Router {
+	r.docs.describe(path, method, op)
+	return r
+}
 
-	go func() {
-		log.Println("Server started on: " + r.port)
-		errs <- server.ListenAndServe()
-	}()
+func (r *ChiRouter) AddLivenessCheck(name string, checker health.Checker, config ...health.Config) Router {
+	r.addCheck(&r.liveness, name, checker, config)
+	return r
+}
 
-	log.Println("exit", <-errs)
+func (r *ChiRouter) AddReadinessCheck(name string, checker health.Checker, config ...health.Config) Router {
+	r.addCheck(&r.readiness, name, checker, config)
+	return r
 }
 
-func (ChiRouter) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	ChiJson(w, 200, map[string]interface{}{
-		"message": "service is running",
-	})
+func (r *ChiRouter) SetShutdownTimeout(timeout time.Duration) Router {
+	r.shutdownTimeout = timeout
+	return r
 }
 
-func (ChiRouter) accessControlMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
+func (r *ChiRouter) SetTLS(config TLSConfig) Router {
+	r.tls = &config
+	return r
+}
 
-		if r.Method == "OPTIONS" {
-			return
-		}
+func (r *ChiRouter) SetServerConfig(config ServerConfig) Router {
+	r.server = config
+	return r
+}
 
-		next.ServeHTTP(w, r)
-	})
+func (r *ChiRouter) OnBeforeShutdown(hook ShutdownHook) Router {
+	r.beforeShutdown = append(r.beforeShutdown, hook)
+	return r
 }
 
-func (r *ChiRouter) AddPath(path, method string, handler http.HandlerFunc) Router {
-	r.handlers[path] = ChiHandle{
-		Method:  method,
-		Handler: handler,
+func (r *ChiRouter) OnAfterShutdown(hook ShutdownHook) Router {
+	r.afterShutdown = append(r.afterShutdown, hook)
+	return r
+}
+
+func (r *ChiRouter) ServeHTTP() {
+	serveUntilSignal(r)
+}
+
+func (r *ChiRouter) Run(ctx context.Context) error {
+	handler, err := r.build()
+	if err != nil {
+		return err
+	}
+
+	return r.serve(ctx, r.httpServer(":"+r.port, handler))
+}
+
+func (r *ChiRouter) Handler() (http.Handler, error) {
+	return r.build()
+}
+
+// build assembles a fresh chi.Mux from the registered middleware and routes.
+func (r *ChiRouter) build() (http.Handler, error) {
+	// middleware
+	middlewares := append([]Middleware{}, r.middleware...)
+	if r.recovery {
+		middlewares = append(middlewares, Recovery)
 	}
+
+	// handler
+	routes, err := r.buildRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	r.router = chi.NewRouter()
+	r.router.NotFound(notFoundHandler)
+	r.router.MethodNotAllowed(methodNotAllowedHandler)
+	for _, h := range middlewares {
+		r.router.Use(h)
+	}
+	register := func(router chi.Router) {
+		for _, h := range routes {
+			log.Printf("api: %s, method: %s", h.Path, h.Method)
+			router.Method(h.Method, h.Path, h.handler(chiParams))
+		}
+	}
+	if r.prefix == "" {
+		register(r.router)
+	} else {
+		r.router.Route(r.prefix, register)
+	}
+	return r.wrap(r.router), nil
+}
+
+func (r *ChiRouter) AddPath(path, method string, handler HandlerFunc, middleware ...Middleware) Router {
+	r.routes.add(path, []string{method}, handler, middleware...)
 	return r
 }
 
-func (r *ChiRouter) AddMiddleware(middleware func(next http.Handler) http.Handler) Router {
+func (r *ChiRouter) AddMethods(path string, methods []string, handler HandlerFunc, middleware ...Middleware) Router {
+	r.routes.add(path, methods, handler, middleware...)
+	return r
+}
+
+func (r *ChiRouter) AddMiddleware(middleware Middleware) Router {
 	r.middleware = append(r.middleware, middleware)
 	return r
 }
 
+func (r *ChiRouter) AddWebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig) Router {
+	r.routes.add(path, []string{"GET"}, r.webSocket(handler, config))
+	return r
+}
+
+func (r *ChiRouter) Group(prefix string, middleware ...Middleware) Group {
+	return r.group(prefix, middleware...)
+}
+
+func chiParams(r *http.Request, name string) string {
+	return chi.URLParam(r, name)
+}
+
 func ChiJson(w http.ResponseWriter, code int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
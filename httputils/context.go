@@ -0,0 +1,97 @@
+package httputils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Context is the backend-neutral view of a request handled by any Router.
+type Context interface {
+	Request() *http.Request
+	Response() http.ResponseWriter
+	Param(name string) string
+	Query(name string) string
+	Header(name string) string
+	// Bind decodes the JSON or form body, query string and path parameters
+	// into v and validates it; see the validation package. Invalid input
+	// returns errs.FieldErrors or an error caused by errs.ErrorInvalidRequest.
+	Bind(v interface{}) error
+	JSON(code int, v interface{}) error
+	String(code int, s string) error
+	NoContent(code int) error
+}
+
+type handlerContext struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	params func(name string) string
+}
+
+func newContext(w http.ResponseWriter, r *http.Request, params func(name string) string) *handlerContext {
+	return &handlerContext{w: w, r: r, params: params}
+}
+
+func (c *handlerContext) Request() *http.Request {
+	return c.r
+}
+
+func (c *handlerContext) Response() http.ResponseWriter {
+	return c.w
+}
+
+func (c *handlerContext) Param(name string) string {
+	if c.params == nil {
+		return ""
+	}
+	return c.params(name)
+}
+
+func (c *handlerContext) Query(name string) string {
+	return c.r.URL.Query().Get(name)
+}
+
+func (c *handlerContext) Header(name string) string {
+	return c.r.Header.Get(name)
+}
+
+func (c *handlerContext) Bind(v interface{}) error {
+	return bind(c.r, c.params, v)
+}
+
+func (c *handlerContext) JSON(code int, v interface{}) error {
+	writeJSON(c.w, code, v)
+	return nil
+}
+
+func (c *handlerContext) String(code int, s string) error {
+	c.w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.w.WriteHeader(code)
+	_, err := c.w.Write([]byte(s))
+	return err
+}
+
+func (c *handlerContext) NoContent(code int) error {
+	c.w.WriteHeader(code)
+	return nil
+}
+
+// serve runs handler h and writes any returned error as a problem response.
+func serve(h HandlerFunc, w http.ResponseWriter, r *http.Request, params func(name string) string) {
+	if err := h(newContext(w, r, params)); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// WrapHandler adapts a plain http.Handler to a HandlerFunc.
+func WrapHandler(h http.Handler) HandlerFunc {
+	return func(c Context) error {
+		h.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,137 @@
+package httputils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CorsConfig is the CORS policy enforced by AllowCors.
+// AllowOrigins accepts "*" and wildcard subdomains such as "https://*.example.com".
+type CorsConfig struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           int // seconds, 0 omits the header
+}
+
+var DefaultCorsConfig = CorsConfig{
+	AllowOrigins: []string{"*"},
+	AllowMethods: []string{
+		http.MethodGet, http.MethodHead, http.MethodPut,
+		http.MethodPatch, http.MethodPost, http.MethodDelete,
+	},
+	AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+}
+
+// Cors returns a middleware enforcing config. Preflight requests are answered
+// with 204 and never reach the handler. It panics when "*" is combined with
+// AllowCredentials, which would let any site make credentialed requests;
+// list the trusted origins instead.
+func Cors(config CorsConfig) Middleware {
+	if config.AllowCredentials {
+		for _, o := range config.AllowOrigins {
+			if o == "*" {
+				panic(`httputils: CORS AllowOrigins "*" cannot be used with AllowCredentials`)
+			}
+		}
+	}
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			header := w.Header()
+			header.Add("Vary", "Origin")
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			allowed, ok := config.allowOrigin(origin)
+			if !ok {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header.Set("Access-Control-Allow-Origin", allowed)
+			if config.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposeHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if config.allowMethod(r.Header.Get("Access-Control-Request-Method")) {
+				header.Set("Access-Control-Allow-Methods", allowMethods)
+				if allowHeaders != "" {
+					header.Set("Access-Control-Allow-Headers", allowHeaders)
+				} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+					header.Set("Access-Control-Allow-Headers", requested)
+				}
+				if config.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin.
+func (c CorsConfig) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	for _, o := range c.AllowOrigins {
+		if o == "*" {
+			return "*", true
+		}
+		if matchOrigin(o, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+func (c CorsConfig) allowMethod(method string) bool {
+	for _, m := range c.AllowMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin reports whether origin matches pattern, where a single "*"
+// stands for one or more subdomain labels.
+func matchOrigin(pattern, origin string) bool {
+	if strings.EqualFold(pattern, origin) {
+		return true
+	}
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return false
+	}
+	prefix, suffix := strings.ToLower(pattern[:i]), strings.ToLower(pattern[i+1:])
+	origin = strings.ToLower(origin)
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix) &&
+		!strings.Contains(origin[len(prefix):len(origin)-len(suffix)], "/")
+}
@@ -0,0 +1,145 @@
+package httputils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{pattern: "https://example.com", origin: "https://example.com", want: true},
+		{pattern: "https://*.example.com", origin: "https://api.example.com", want: true},
+		{pattern: "https://*.example.com", origin: "https://a.b.example.com", want: true},
+		{pattern: "https://*.example.com", origin: "https://example.com", want: false},
+		{pattern: "https://*.example.com", origin: "http://api.example.com", want: false},
+		{pattern: "https://*.example.com", origin: "https://evil.com/.example.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.origin, func(t *testing.T) {
+			if got := matchOrigin(tt.pattern, tt.origin); got != tt.want {
+				t.Errorf("matchOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCors(t *testing.T) {
+	config := CorsConfig{
+		AllowOrigins:     []string{"https://*.example.com"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowHeaders:     []string{"Content-Type"},
+		ExposeHeaders:    []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		header     map[string]string
+		wantStatus int
+		wantHeader map[string]string
+	}{
+		{
+			name:       "allowed preflight",
+			method:     http.MethodOptions,
+			header:     map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Content-Type",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			name:       "preflight with disallowed method",
+			method:     http.MethodOptions,
+			header:     map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "DELETE"},
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{"Access-Control-Allow-Methods": ""},
+		},
+		{
+			name:       "disallowed origin",
+			method:     http.MethodGet,
+			header:     map[string]string{"Origin": "https://evil.com"},
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:       "actual request",
+			method:     http.MethodGet,
+			header:     map[string]string{"Origin": "https://app.example.com"},
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{
+				"Access-Control-Allow-Origin":   "https://app.example.com",
+				"Access-Control-Expose-Headers": "X-Request-ID",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			Cors(config)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			for k, v := range tt.wantHeader {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowCorsPreflight(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").
+				AddPath("/users", "GET", func(c Context) error { return c.NoContent(http.StatusOK) }).
+				AllowCors()
+			addr, stop := start(t, r)
+			defer stop()
+
+			req, _ := http.NewRequest(http.MethodOptions, "http://"+addr+"/users", nil)
+			req.Header.Set("Origin", "https://app.example.com")
+			req.Header.Set("Access-Control-Request-Method", "PUT")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "*" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, "*")
+			}
+		})
+	}
+}
+
+func TestCorsWildcardCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Cors() did not panic for \"*\" with AllowCredentials")
+		}
+	}()
+	Cors(CorsConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
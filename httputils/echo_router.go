@@ -2,31 +2,23 @@ package httputils
 
 import (
 	"context"
-	"log"
 	"net/http"
-	"os"
-	"os/signal"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-)
+	"github.com/pkg/errors"
 
-type EchoHandle struct {
-	Method  string
-	Handler echo.HandlerFunc
-}
+	"github.com/huylqbk/codesample/errs"
+	"github.com/huylqbk/codesample/health"
+)
 
 type EchoRouter struct {
-	port        string
-	router      *echo.Echo
-	prefix      string
-	middleware  []echo.MiddlewareFunc
-	handlers    map[string]EchoHandle
-	healthCheck bool
-	logRequest  bool
-	cors        bool
-	recovery    bool
+	port       string
+	router     *echo.Echo
+	middleware []echo.MiddlewareFunc
+	options
 }
 
 func NewEchoRouter(port string) Router {
@@ -34,7 +26,6 @@ func NewEchoRouter(port string) Router {
 		port:       port,
 		router:     echo.New(),
 		middleware: make([]echo.MiddlewareFunc, 0),
-		handlers:   make(map[string]EchoHandle, 0),
 	}
 }
 
@@ -44,6 +35,7 @@ func (r *EchoRouter) Default() {
 		AllowHealthCheck().
 		AllowLog().
 		AllowRecovery().
+		AllowRequestID().
 		ServeHTTP()
 }
 
@@ -57,8 +49,12 @@ func (r *EchoRouter) AllowRecovery() Router {
 	return r
 }
 
-func (r *EchoRouter) AllowLog() Router {
-	r.logRequest = true
+func (r *EchoRouter) AllowLog(config ...AccessLogConfig) Router {
+	var accessLog AccessLogConfig
+	if len(config) > 0 {
+		accessLog = config[0]
+	}
+	r.accessLog = &accessLog
 	return r
 }
 
@@ -67,78 +63,195 @@ func (r *EchoRouter) AllowHealthCheck() Router {
 	return r
 }
 
-func (r *EchoRouter) AllowCors() Router {
-	r.cors = true
+func (r *EchoRouter) AllowCors(config ...CorsConfig) Router {
+	cors := DefaultCorsConfig
+	if len(config) > 0 {
+		cors = config[0]
+	}
+	r.cors = &cors
 	return r
 }
 
-func (r *EchoRouter) ServeHTTP() {
-	// middleware
-	if r.logRequest {
-		r.AddMiddleware(middleware.Logger())
-	}
-	if r.cors {
-		r.AddMiddleware(middleware.CORS())
-	}
-	// default enable gzip
-	r.AddMiddleware(middleware.Gzip())
+func (r *EchoRouter) AllowMetrics() Router {
+	r.metrics = true
+	return r
+}
 
-	for _, h := range r.middleware {
-		r.router.Use(h)
+func (r *EchoRouter) AllowRequestID() Router {
+	r.requestID = true
+	return r
+}
+
+func (r *EchoRouter) AllowOpenAPI(config ...OpenAPIConfig) Router {
+	openapi := DefaultOpenAPIConfig
+	if len(config) > 0 {
+		openapi = config[0]
 	}
-	shutdownCtx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer cancel()
+	r.openapi = &openapi
+	return r
+}
 
-	r.router.POST("/quit", func(c echo.Context) error {
-		cancel()
-		return c.String(http.StatusOK, "OK")
-	})
+func (r *EchoRouter) AllowAdmin(config AdminConfig) Router {
+	r.admin = &config
+	return r
+}
 
-	// handler
-	if r.healthCheck {
-		r.AddPath("/health", "GET", r.healthCheckHandler)
-	}
-	e := r.router.Group(r.prefix)
+func (r *EchoRouter) Describe(path, method string, op Operation) Router {
+	r.docs.describe(path, method, op)
+	return r
+}
+
+func (r *EchoRouter) AddLivenessCheck(name string, checker health.Checker, config ...health.Config) Router {
+	r.addCheck(&r.liveness, name, checker, config)
+	return r
+}
+
+func (r *EchoRouter) AddReadinessCheck(name string, checker health.Checker, config ...health.Config) Router {
+	r.addCheck(&r.readiness, name, checker, config)
+	return r
+}
+
+func (r *EchoRouter) SetShutdownTimeout(timeout time.Duration) Router {
+	r.shutdownTimeout = timeout
+	return r
+}
+
+func (r *EchoRouter) SetTLS(config TLSConfig) Router {
+	r.tls = &config
+	return r
+}
+
+func (r *EchoRouter) SetServerConfig(config ServerConfig) Router {
+	r.server = config
+	return r
+}
+
+func (r *EchoRouter) OnBeforeShutdown(hook ShutdownHook) Router {
+	r.beforeShutdown = append(r.beforeShutdown, hook)
+	return r
+}
+
+func (r *EchoRouter) OnAfterShutdown(hook ShutdownHook) Router {
+	r.afterShutdown = append(r.afterShutdown, hook)
+	return r
+}
 
-	for p, h := range r.handlers {
-		e.Add(h.Method, p, h.Handler)
+func (r *EchoRouter) ServeHTTP() {
+	serveUntilSignal(r)
+}
+
+func (r *EchoRouter) Run(ctx context.Context) error {
+	handler, err := r.build()
+	if err != nil {
+		return err
 	}
 
+	return r.serve(ctx, r.httpServer(":"+r.port, handler))
+}
+
+func (r *EchoRouter) Handler() (http.Handler, error) {
+	return r.build()
+}
+
+// build assembles a fresh echo.Echo from the registered middleware and routes.
+func (r *EchoRouter) build() (http.Handler, error) {
+	// middleware
+	middlewares := append([]echo.MiddlewareFunc{}, r.middleware...)
 	if r.recovery {
-		e.Use(middleware.Recover())
+		middlewares = append(middlewares, echo.WrapMiddleware(Recovery))
+	}
+	// default enable gzip, except for event streams that must flush as they
+	// go and websocket upgrades
+	middlewares = append(middlewares, middleware.GzipWithConfig(middleware.GzipConfig{
+		Skipper: func(c echo.Context) bool {
+			return acceptsEventStream(c.Request()) || websocket.IsWebSocketUpgrade(c.Request())
+		},
+	}))
+
+	// handler
+	routes, err := r.buildRoutes()
+	if err != nil {
+		return nil, err
 	}
 
-	// server
-	go func() {
-		log.Println("Server started on: " + r.port)
-		if err := r.router.Start(":" + r.port); err != nil && err != http.ErrServerClosed {
-			log.Fatal("shutting down the server")
+	paths := make([]string, len(routes))
+	for i, h := range routes {
+		if paths[i], err = echoPath(h.Path); err != nil {
+			return nil, err
 		}
-	}()
-	<-shutdownCtx.Done()
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	log.Println("router stopped")
-	if err := r.router.Shutdown(ctx); err != nil {
-		log.Fatal(err)
 	}
+
+	r.router = echo.New()
+	r.router.HTTPErrorHandler = echoErrorHandler
+	for _, h := range middlewares {
+		r.router.Use(h)
+	}
+	e := r.router.Group(r.prefix)
+	for i, h := range routes {
+		e.Add(h.Method, paths[i], echoHandler(h.handler(echoParams)))
+	}
+	return r.wrap(r.router), nil
 }
 
-func (EchoRouter) healthCheckHandler(c echo.Context) error {
-	return c.JSON(200, map[string]interface{}{
-		"message": "service is running",
-	})
+func (r *EchoRouter) AddPath(path, method string, handler HandlerFunc, middleware ...Middleware) Router {
+	r.routes.add(path, []string{method}, handler, middleware...)
+	return r
 }
 
-func (r *EchoRouter) AddPath(path, method string, handler echo.HandlerFunc) *EchoRouter {
-	r.handlers[path] = EchoHandle{
-		Method:  method,
-		Handler: handler,
-	}
+func (r *EchoRouter) AddMethods(path string, methods []string, handler HandlerFunc, middleware ...Middleware) Router {
+	r.routes.add(path, methods, handler, middleware...)
+	return r
+}
+
+func (r *EchoRouter) AddMiddleware(middleware Middleware) Router {
+	r.middleware = append(r.middleware, echo.WrapMiddleware(middleware))
 	return r
 }
 
-func (r *EchoRouter) AddMiddleware(middleware echo.MiddlewareFunc) *EchoRouter {
-	r.middleware = append(r.middleware, middleware)
+func (r *EchoRouter) AddWebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig) Router {
+	r.routes.add(path, []string{"GET"}, r.webSocket(handler, config))
 	return r
 }
+
+func (r *EchoRouter) Group(prefix string, middleware ...Middleware) Group {
+	return r.group(prefix, middleware...)
+}
+
+type echoContextKey struct{}
+
+func echoHandler(h http.Handler) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := c.Request()
+		h.ServeHTTP(c.Response(), r.WithContext(context.WithValue(r.Context(), echoContextKey{}, c)))
+		return nil
+	}
+}
+
+// echoErrorHandler writes errors raised by echo itself, such as unmatched
+// routes, as problem responses.
+func echoErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		WriteError(c.Response(), c.Request(), err)
+		return
+	}
+	switch he.Code {
+	case http.StatusNotFound:
+		WriteError(c.Response(), c.Request(), errs.ErrorNotFound)
+	case http.StatusMethodNotAllowed:
+		WriteError(c.Response(), c.Request(), errs.ErrorMethodNotAllowed)
+	default:
+		newStatusProblem(c.Request(), he.Code).Write(c.Response())
+	}
+}
+
+func echoParams(r *http.Request, name string) string {
+	c, ok := r.Context().Value(echoContextKey{}).(echo.Context)
+	if !ok {
+		return ""
+	}
+	return c.Param(name)
+}
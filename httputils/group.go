@@ -0,0 +1,75 @@
+package httputils
+
+import "strings"
+
+// Group registers routes under a common prefix with its own middleware,
+// which runs after the Router middleware and before the route middleware.
+// Groups nest; an inner group runs its parent's middleware first.
+type Group interface {
+	Group(prefix string, middleware ...Middleware) Group
+	AddPath(path, method string, handler HandlerFunc, middleware ...Middleware) Group
+	AddMethods(path string, methods []string, handler HandlerFunc, middleware ...Middleware) Group
+	AddWebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig) Group
+	AddMiddleware(middleware Middleware) Group
+	Describe(path, method string, op Operation) Group
+}
+
+type routeGroup struct {
+	options    *options
+	parent     *routeGroup
+	prefix     string
+	middleware []Middleware
+}
+
+func (o *options) group(prefix string, middleware ...Middleware) Group {
+	return &routeGroup{options: o, prefix: prefix, middleware: middleware}
+}
+
+func (g *routeGroup) Group(prefix string, middleware ...Middleware) Group {
+	return &routeGroup{options: g.options, parent: g, prefix: prefix, middleware: middleware}
+}
+
+func (g *routeGroup) AddPath(path, method string, handler HandlerFunc, middleware ...Middleware) Group {
+	return g.AddMethods(path, []string{method}, handler, middleware...)
+}
+
+func (g *routeGroup) AddMethods(path string, methods []string, handler HandlerFunc, middleware ...Middleware) Group {
+	rs := &g.options.routes
+	n := len(*rs)
+	rs.add(g.path(path), methods, handler, middleware...)
+	for i := n; i < len(*rs); i++ {
+		(*rs)[i].group = g
+	}
+	return g
+}
+
+func (g *routeGroup) AddWebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig) Group {
+	return g.AddPath(path, "GET", g.options.webSocket(handler, config))
+}
+
+// AddMiddleware applies to every route of the group, including those added before.
+func (g *routeGroup) AddMiddleware(middleware Middleware) Group {
+	g.middleware = append(g.middleware, middleware)
+	return g
+}
+
+func (g *routeGroup) Describe(path, method string, op Operation) Group {
+	g.options.docs.describe(g.path(path), method, op)
+	return g
+}
+
+// path returns path under the prefixes of g and its parents.
+func (g *routeGroup) path(path string) string {
+	for ; g != nil; g = g.parent {
+		path = strings.TrimSuffix(g.prefix, "/") + path
+	}
+	return path
+}
+
+// chain returns the middleware of g's parents followed by its own.
+func (g *routeGroup) chain() []Middleware {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.chain(), g.middleware...)
+}
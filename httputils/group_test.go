@@ -0,0 +1,101 @@
+package httputils
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// trace appends name to the X-Trace response header.
+func trace(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestGroup(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			ok := func(c Context) error { return c.String(http.StatusOK, c.Param("id")) }
+			r := newRouter("0").AddPrefix("/api").AddMiddleware(trace("router"))
+			v1 := r.Group("/v1", trace("v1"))
+			v1.AddPath("/users/{id}", "GET", ok, trace("route"))
+			admin := v1.Group("/admin").AddPath("/users/{id}", "DELETE", ok)
+			admin.AddMiddleware(trace("admin"))
+			r.AddPath("/users/{id}", "GET", ok)
+			addr, stop := start(t, r)
+			defer stop()
+
+			tests := []struct {
+				method    string
+				path      string
+				wantTrace string
+			}{
+				{http.MethodGet, "/api/v1/users/1", "router,v1,route"},
+				{http.MethodDelete, "/api/v1/admin/users/2", "router,v1,admin"},
+				{http.MethodGet, "/api/users/3", "router"},
+			}
+			for _, tt := range tests {
+				req, _ := http.NewRequest(tt.method, "http://"+addr+tt.path, nil)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK || string(body) != tt.path[len(tt.path)-1:] {
+					t.Errorf("%s %s = %d %q", tt.method, tt.path, resp.StatusCode, body)
+				}
+				if got := strings.Join(resp.Header.Values("X-Trace"), ","); got != tt.wantTrace {
+					t.Errorf("%s %s: middleware = %q, want %q", tt.method, tt.path, got, tt.wantTrace)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupDuplicateRoute(t *testing.T) {
+	r := NewChiRouter("0").AddPath("/v1/users", "GET", WrapHandler(http.NotFoundHandler()))
+	r.Group("/v1").AddPath("/users", "GET", WrapHandler(http.NotFoundHandler()))
+	if _, err := r.(*ChiRouter).build(); err == nil || !strings.Contains(err.Error(), "GET /v1/users") {
+		t.Errorf("build() error = %v, want duplicate route", err)
+	}
+}
+
+func TestMiddlewareUnmatched(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			ok := func(c Context) error { return c.NoContent(http.StatusOK) }
+			r := newRouter("0").AddPrefix("/api").AddMiddleware(trace("router")).AddPath("/users", "GET", ok)
+			addr, stop := start(t, r)
+			defer stop()
+
+			tests := []struct {
+				method string
+				path   string
+				want   int
+			}{
+				{http.MethodGet, "/api/missing", http.StatusNotFound},
+				{http.MethodDelete, "/api/users", http.StatusMethodNotAllowed},
+			}
+			for _, tt := range tests {
+				req, _ := http.NewRequest(tt.method, "http://"+addr+tt.path, nil)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.want {
+					t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+				}
+				if got := resp.Header.Get("X-Trace"); got != "router" {
+					t.Errorf("%s %s: middleware = %q, want %q", tt.method, tt.path, got, "router")
+				}
+			}
+		})
+	}
+}
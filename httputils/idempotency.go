@@ -0,0 +1,324 @@
+package httputils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotency-Replayed"
+
+	defaultIdempotencyTTL         = 24 * time.Hour
+	defaultIdempotencyLockTimeout = time.Minute
+	maxIdempotencyKeyLength       = 255
+)
+
+var (
+	// ErrIdempotencyInProgress is returned by IdempotencyStore.Begin while
+	// another request holds the key.
+	ErrIdempotencyInProgress = errors.New("idempotency key is in use by a request in progress")
+	// ErrIdempotencyMismatch is returned by IdempotencyStore.Begin when the
+	// key was first used for a different request.
+	ErrIdempotencyMismatch = errors.New("idempotency key was used for a different request")
+)
+
+// IdempotentResponse is a stored response replayed for repeated keys.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore keeps responses by key. Begin reserves a new key and
+// returns a token that owns the reservation, returns the completed response
+// of a known key, or fails with ErrIdempotencyInProgress while another
+// request holds the key. Complete stores the response for ttl and Abort
+// releases the key so the request can be retried; both only act on the
+// reservation of token. A store shared between instances may hand over a
+// reservation older than lockTimeout, as its owner has crashed or, since
+// the middleware cancels requests at LockTimeout, given up.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (token string, stored *IdempotentResponse, err error)
+	Complete(ctx context.Context, key, token string, response IdempotentResponse, ttl time.Duration) error
+	Abort(ctx context.Context, key, token string) error
+}
+
+type IdempotencyConfig struct {
+	Store       IdempotencyStore // defaults to NewMemoryIdempotencyStore
+	TTL         time.Duration    // how long responses are replayed, defaults to 24 hours
+	LockTimeout time.Duration    // how long a request may run holding a key, defaults to 1 minute
+	Methods     []string         // defaults to POST and PUT
+	Required    bool             // reject requests without a key
+	// Scope separates the keys of different clients, such as by user ID;
+	// defaults to none.
+	Scope func(r *http.Request) string
+}
+
+// replaySkipHeaders belong to the original response only and are not
+// stored for replays.
+var replaySkipHeaders = map[string]bool{
+	"Date":              true,
+	"Content-Length":    true,
+	HeaderRequestID:     true,
+	HeaderCorrelationID: true,
+}
+
+// Idempotency replays the first response to a request with an
+// Idempotency-Key header for repeats with the same key. A repeat while the
+// first is still running gets 409, and a key reused for a different method,
+// path or body gets 400. Server errors are not stored, so the request can be
+// retried.
+func Idempotency(config IdempotencyConfig) Middleware {
+	if config.Store == nil {
+		config.Store = NewMemoryIdempotencyStore()
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultIdempotencyTTL
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = defaultIdempotencyLockTimeout
+	}
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodPost, http.MethodPut}
+	}
+	methods := make(map[string]bool, len(config.Methods))
+	for _, m := range config.Methods {
+		methods[strings.ToUpper(m)] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !methods[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := r.Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				if config.Required {
+					WriteError(w, r, errs.FieldErrors{{Field: HeaderIdempotencyKey, Message: "is required"}})
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				WriteError(w, r, errs.FieldErrors{{Field: HeaderIdempotencyKey, Message: "must have at most 255 characters"}})
+				return
+			}
+			if config.Scope != nil {
+				key = config.Scope(r) + ":" + key
+			}
+
+			fingerprint, err := requestFingerprint(r)
+			if err != nil {
+				WriteError(w, r, bodyError(err))
+				return
+			}
+			token, stored, err := config.Store.Begin(r.Context(), key, fingerprint, config.LockTimeout)
+			switch {
+			case errors.Is(err, ErrIdempotencyInProgress):
+				WriteError(w, r, errors.Wrap(errs.ErrorConflict, err.Error()))
+				return
+			case errors.Is(err, ErrIdempotencyMismatch):
+				WriteError(w, r, errors.Wrap(errs.ErrorInvalidRequest, err.Error()))
+				return
+			case err != nil:
+				WriteError(w, r, err)
+				return
+			case stored != nil:
+				replay(w, *stored)
+				return
+			}
+
+			rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				// release the key if the handler panicked
+				if !completed {
+					config.Store.Abort(context.Background(), key, token)
+				}
+			}()
+			// the request stops before its reservation may be handed over
+			ctx, cancel := context.WithTimeout(r.Context(), config.LockTimeout)
+			defer cancel()
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			completed = true
+
+			if rec.status >= http.StatusInternalServerError {
+				err = config.Store.Abort(context.Background(), key, token)
+			} else {
+				err = config.Store.Complete(context.Background(), key, token, rec.response(), config.TTL)
+			}
+			if err != nil {
+				log.Println("idempotency:", err)
+			}
+		})
+	}
+}
+
+// requestFingerprint hashes the method, path and body of r, leaving the body readable.
+func requestFingerprint(r *http.Request) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func replay(w http.ResponseWriter, response IdempotentResponse) {
+	header := w.Header()
+	for name, values := range response.Header {
+		header[name] = values
+	}
+	header.Set(HeaderIdempotencyReplayed, "true")
+	w.WriteHeader(response.Status)
+	w.Write(response.Body)
+}
+
+// idempotencyRecorder copies a response as it is written.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *idempotencyRecorder) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = code
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *idempotencyRecorder) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *idempotencyRecorder) response() IdempotentResponse {
+	header := w.header
+	if header == nil {
+		header = w.ResponseWriter.Header().Clone()
+	}
+	for name := range replaySkipHeaders {
+		header.Del(name)
+	}
+	return IdempotentResponse{Status: w.status, Header: header, Body: w.body.Bytes()}
+}
+
+// newIdempotencyToken returns a random owner for a reservation.
+func newIdempotencyToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	token       string
+	response    *IdempotentResponse
+	expires     time.Time // of the stored response
+}
+
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryIdempotencyStore keeps responses in process memory. Its
+// reservations are never handed over: their owner always releases them.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (string, *IdempotentResponse, error) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && (e.response == nil || now.Before(e.expires)) {
+		if e.fingerprint != fingerprint {
+			return "", nil, ErrIdempotencyMismatch
+		}
+		if e.response == nil {
+			return "", nil, ErrIdempotencyInProgress
+		}
+		return "", e.response, nil
+	}
+	token, err := newIdempotencyToken()
+	if err != nil {
+		return "", nil, err
+	}
+	s.entries[key] = &idempotencyEntry{fingerprint: fingerprint, token: token}
+	return token, nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, key, token string, response IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && e.response == nil && e.token == token {
+		e.response = &response
+		e.expires = s.now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Abort(ctx context.Context, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && e.response == nil && e.token == token {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// sweep drops expired responses, at most once per sweep interval.
+func (s *memoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if e.response != nil && !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
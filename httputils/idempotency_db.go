@@ -0,0 +1,141 @@
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/huylqbk/codesample/database"
+)
+
+// idempotencyRecord is a row of the idempotency_keys table.
+type idempotencyRecord struct {
+	Key         string `gorm:"column:idempotency_key;primaryKey;size:512"`
+	Fingerprint string `gorm:"size:64;not null"`
+	Token       string `gorm:"size:32;not null;default:''"` // owner of the reservation
+	Status      int    `gorm:"not null;default:0"`
+	Header      []byte // JSON encoded http.Header
+	Body        []byte
+	Completed   bool      `gorm:"not null;default:false"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
+
+func (idempotencyRecord) TableName() string {
+	return "idempotency_keys"
+}
+
+type databaseIdempotencyStore struct {
+	db database.Database
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewDatabaseIdempotencyStore keeps responses in the idempotency_keys table
+// of db, which it creates if needed, so every instance of a service shares
+// the keys. Keys are reserved with an insert, so two instances cannot both
+// run a request; a reservation is only handed over once it is older than
+// the lock timeout, when its instance has crashed.
+func NewDatabaseIdempotencyStore(db database.Database) (IdempotencyStore, error) {
+	if err := db.GetDB().AutoMigrate(&idempotencyRecord{}); err != nil {
+		return nil, errors.Wrap(err, "create idempotency_keys")
+	}
+	return &databaseIdempotencyStore{db: db}, nil
+}
+
+func (s *databaseIdempotencyStore) Begin(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (string, *IdempotentResponse, error) {
+	tx := s.db.GetDB().WithContext(ctx)
+	now := time.Now()
+	s.sweep(tx, now)
+
+	err := tx.Where("idempotency_key = ? AND expires_at <= ?", key, now).Delete(&idempotencyRecord{}).Error
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := newIdempotencyToken()
+	if err != nil {
+		return "", nil, err
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&idempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Token:       token,
+		ExpiresAt:   now.Add(lockTimeout),
+	})
+	if result.Error != nil {
+		return "", nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return token, nil, nil
+	}
+
+	var record idempotencyRecord
+	err = tx.Where("idempotency_key = ?", key).Take(&record).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// released by its holder since the insert
+		return "", nil, ErrIdempotencyInProgress
+	case err != nil:
+		return "", nil, err
+	case record.Fingerprint != fingerprint:
+		return "", nil, ErrIdempotencyMismatch
+	case !record.Completed:
+		return "", nil, ErrIdempotencyInProgress
+	}
+
+	response := &IdempotentResponse{Status: record.Status, Body: record.Body}
+	if len(record.Header) > 0 {
+		if err := json.Unmarshal(record.Header, &response.Header); err != nil {
+			return "", nil, errors.Wrap(err, "decode stored header")
+		}
+	}
+	if response.Header == nil {
+		response.Header = http.Header{}
+	}
+	return "", response, nil
+}
+
+func (s *databaseIdempotencyStore) Complete(ctx context.Context, key, token string, response IdempotentResponse, ttl time.Duration) error {
+	header, err := json.Marshal(response.Header)
+	if err != nil {
+		return err
+	}
+	return s.db.GetDB().WithContext(ctx).
+		Model(&idempotencyRecord{}).
+		Where("idempotency_key = ? AND token = ? AND completed = ?", key, token, false).
+		Updates(map[string]interface{}{
+			"status":     response.Status,
+			"header":     header,
+			"body":       response.Body,
+			"completed":  true,
+			"expires_at": time.Now().Add(ttl),
+		}).Error
+}
+
+func (s *databaseIdempotencyStore) Abort(ctx context.Context, key, token string) error {
+	return s.db.GetDB().WithContext(ctx).
+		Where("idempotency_key = ? AND token = ? AND completed = ?", key, token, false).
+		Delete(&idempotencyRecord{}).Error
+}
+
+// sweep deletes expired rows, at most once per sweep interval.
+func (s *databaseIdempotencyStore) sweep(tx *gorm.DB, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	if err := tx.Where("expires_at <= ?", now).Delete(&idempotencyRecord{}).Error; err != nil {
+		log.Println("idempotency sweep:", err)
+	}
+}
@@ -0,0 +1,170 @@
+package httputils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryIdempotencyStore().(*memoryIdempotencyStore)
+	store.now = func() time.Time { return now }
+
+	token, stored, err := store.Begin(ctx, "k", "a", time.Minute)
+	if token == "" || stored != nil || err != nil {
+		t.Fatalf("first Begin = %q, %v, %v, want a token", token, stored, err)
+	}
+	if _, _, err := store.Begin(ctx, "k", "a", time.Minute); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("Begin while in progress: err = %v, want %v", err, ErrIdempotencyInProgress)
+	}
+	if _, _, err := store.Begin(ctx, "k", "b", time.Minute); !errors.Is(err, ErrIdempotencyMismatch) {
+		t.Errorf("Begin with other fingerprint: err = %v, want %v", err, ErrIdempotencyMismatch)
+	}
+
+	// a slow request keeps its key past the lock timeout
+	now = now.Add(time.Hour)
+	if _, _, err := store.Begin(ctx, "k", "a", time.Minute); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("Begin after the lock timeout: err = %v, want %v", err, ErrIdempotencyInProgress)
+	}
+	store.Abort(ctx, "k", "other")
+	store.Complete(ctx, "k", "other", IdempotentResponse{Status: http.StatusTeapot}, time.Hour)
+	if _, _, err := store.Begin(ctx, "k", "a", time.Minute); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("Begin after Abort and Complete with another token: err = %v, want %v", err, ErrIdempotencyInProgress)
+	}
+
+	store.Complete(ctx, "k", token, IdempotentResponse{Status: http.StatusCreated, Body: []byte("done")}, time.Hour)
+	_, stored, err = store.Begin(ctx, "k", "a", time.Minute)
+	if err != nil || stored == nil || stored.Status != http.StatusCreated || string(stored.Body) != "done" {
+		t.Errorf("Begin after Complete = %+v, %v, want stored response", stored, err)
+	}
+
+	now = now.Add(time.Hour)
+	token, stored, err = store.Begin(ctx, "k", "b", time.Minute)
+	if token == "" || stored != nil || err != nil {
+		t.Errorf("Begin after expiry = %q, %v, %v, want a token", token, stored, err)
+	}
+
+	store.Abort(ctx, "k", token)
+	if token, stored, err := store.Begin(ctx, "k", "c", time.Minute); token == "" || stored != nil || err != nil {
+		t.Errorf("Begin after Abort = %q, %v, %v, want a token", token, stored, err)
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	handler := Idempotency(IdempotencyConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Order", fmt.Sprint(n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "order %d", n)
+	}))
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do(http.MethodPost, "/orders", "k1", `{"item":1}`)
+	replay := do(http.MethodPost, "/orders", "k1", `{"item":1}`)
+	if first.Code != http.StatusCreated || replay.Code != http.StatusCreated {
+		t.Fatalf("status = %d, %d, want %d", first.Code, replay.Code, http.StatusCreated)
+	}
+	if replay.Body.String() != "order 1" || replay.Header().Get("X-Order") != "1" {
+		t.Errorf("replay = %q, X-Order %q, want the first response", replay.Body.String(), replay.Header().Get("X-Order"))
+	}
+	if replay.Header().Get(HeaderIdempotencyReplayed) != "true" {
+		t.Errorf("%s header is missing", HeaderIdempotencyReplayed)
+	}
+	if first.Header().Get(HeaderIdempotencyReplayed) != "" {
+		t.Errorf("%s set on the first response", HeaderIdempotencyReplayed)
+	}
+
+	if rec := do(http.MethodPost, "/orders", "k1", `{"item":2}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused key: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := do(http.MethodPost, "/orders?coupon=x", "k1", `{"item":1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused key with another query: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := do(http.MethodPost, "/orders", "", `{"item":1}`); rec.Body.String() != "order 2" {
+		t.Errorf("without key: body = %q, want a new order", rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/orders", "k1", ""); rec.Header().Get(HeaderIdempotencyReplayed) != "" {
+		t.Errorf("GET was replayed")
+	}
+
+	// a failed request is not stored and may be retried
+	do(http.MethodPost, "/fail", "k2", "")
+	if rec := do(http.MethodPost, "/fail", "k2", ""); rec.Header().Get(HeaderIdempotencyReplayed) != "" {
+		t.Errorf("server error was replayed")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		do(http.MethodPut, "/slow", "k3", "")
+		close(done)
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&calls) == 6 })
+	if rec := do(http.MethodPut, "/slow", "k3", ""); rec.Code != http.StatusConflict {
+		t.Errorf("concurrent duplicate: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	close(release)
+	<-done
+}
+
+func TestIdempotencyLockTimeout(t *testing.T) {
+	handler := Idempotency(IdempotencyConfig{LockTimeout: 10 * time.Millisecond})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(HeaderIdempotencyKey, "k1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the request cancelled at LockTimeout", rec.Code)
+	}
+}
+
+func TestIdempotencyBodyTooLarge(t *testing.T) {
+	handler := MaxBodySize(4)(Idempotency(IdempotencyConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"item":1}`))
+	req.Header.Set(HeaderIdempotencyKey, "k1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestIdempotencyRequired(t *testing.T) {
+	handler := Idempotency(IdempotencyConfig{Required: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,212 @@
+package httputils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSCacheTTL = 10 * time.Minute
+	jwksMinRefresh      = time.Minute
+	jwksFetchTimeout    = 10 * time.Second
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwks loads a JSON Web Key Set from a file or URL and caches it for ttl.
+// An unknown kid triggers a refresh, at most once per minute. Concurrent
+// callers share one fetch, which runs without holding the lock.
+type jwks struct {
+	file string
+	url  string
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	fetching  chan struct{} // closed when the running fetch is done
+	fetchErr  error
+}
+
+func newJWKS(file, url string, ttl time.Duration) *jwks {
+	if ttl <= 0 {
+		ttl = defaultJWKSCacheTTL
+	}
+	return &jwks{file: file, url: url, ttl: ttl}
+}
+
+// key returns the public key for kid; an empty kid matches a set with a single key.
+func (s *jwks) key(kid string) (interface{}, error) {
+	s.mu.Lock()
+	age := time.Since(s.fetchedAt)
+	key, ok := s.lookup(kid)
+	loaded := s.keys != nil
+	s.mu.Unlock()
+
+	switch {
+	case ok && age > s.ttl:
+		// the cached key stays valid while the set is refreshed
+		go s.refresh()
+	case !ok && (!loaded || age > jwksMinRefresh):
+		err := s.refresh()
+		s.mu.Lock()
+		key, ok = s.lookup(kid)
+		loaded = s.keys != nil
+		s.mu.Unlock()
+		if err != nil && !loaded {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("jwks: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (s *jwks) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the set, or waits for the fetch already running.
+func (s *jwks) refresh() error {
+	s.mu.Lock()
+	if wait := s.fetching; wait != nil {
+		s.mu.Unlock()
+		<-wait
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.fetchErr
+	}
+	done := make(chan struct{})
+	s.fetching = done
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	keys, err := s.fetch()
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.fetchErr = err
+	s.fetching = nil
+	s.mu.Unlock()
+	close(done)
+	return err
+}
+
+// fetch reads and parses the set. Keys of a type or curve that is not
+// supported, such as Ed25519, are skipped so they don't reject every token.
+func (s *jwks) fetch() (map[string]interface{}, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.Printf("jwks: skipping key %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (s *jwks) read() ([]byte, error) {
+	if s.file != "" {
+		return os.ReadFile(s.file)
+	}
+	client := &http.Client{Timeout: jwksFetchTimeout}
+	response, err := client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: %s returned status %d", s.url, response.StatusCode)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwks: unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("jwks: unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
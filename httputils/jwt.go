@@ -0,0 +1,147 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+// JWTConfig configures the JWT middleware. HS256 tokens are verified with
+// Secret; RS256 and ES256 tokens with the JWKS key matching their kid, or
+// with PublicKey when they carry no kid or no JWKS is set.
+type JWTConfig struct {
+	Algorithms    []string    // accepted algorithms, defaults to HS256, RS256 and ES256
+	Secret        []byte      // HMAC key
+	PublicKey     interface{} // *rsa.PublicKey or *ecdsa.PublicKey
+	JWKSFile      string
+	JWKSURL       string
+	JWKSCacheTTL  time.Duration // defaults to 10 minutes
+	Audience      string
+	Issuer        string
+	RequireExpiry bool
+	NewClaims     func() jwt.Claims // defaults to *jwt.RegisteredClaims
+	// Authorize runs after the token is validated; an error rejects the
+	// request with errs.ErrorForbidden.
+	Authorize func(r *http.Request, claims jwt.Claims) error
+}
+
+type jwtClaimsKey struct{}
+
+// JWT authenticates "Authorization: Bearer" tokens and stores the claims in
+// the request context. Invalid or missing tokens get errs.ErrorUnauthorized.
+func JWT(config JWTConfig) Middleware {
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{"HS256", "RS256", "ES256"}
+	}
+	if config.NewClaims == nil {
+		config.NewClaims = func() jwt.Claims { return &jwt.RegisteredClaims{} }
+	}
+	var keys *jwks
+	if config.JWKSFile != "" || config.JWKSURL != "" {
+		keys = newJWKS(config.JWKSFile, config.JWKSURL, config.JWKSCacheTTL)
+	}
+	parser := jwt.NewParser(jwt.WithValidMethods(config.Algorithms))
+
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			if len(config.Secret) == 0 {
+				return nil, errors.New("no secret for " + token.Method.Alg())
+			}
+			return config.Secret, nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		if config.PublicKey != nil && (kid == "" || keys == nil) {
+			return config.PublicKey, nil
+		}
+		if keys != nil {
+			return keys.key(kid)
+		}
+		return nil, errors.New("no public key for " + token.Method.Alg())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				WriteError(w, r, errors.Wrap(errs.ErrorUnauthorized, "missing bearer token"))
+				return
+			}
+
+			claims := config.NewClaims()
+			if _, err := parser.ParseWithClaims(raw, claims, keyFunc); err != nil {
+				unauthorized(w, r, err)
+				return
+			}
+			if err := config.verify(claims); err != nil {
+				unauthorized(w, r, err)
+				return
+			}
+			if config.Authorize != nil {
+				if err := config.Authorize(r, claims); err != nil {
+					WriteError(w, r, errors.Wrap(errs.ErrorForbidden, err.Error()))
+					return
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), jwtClaimsKey{}, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// JWTClaims returns the claims stored by the JWT middleware as T, the type
+// returned by JWTConfig.NewClaims.
+func JWTClaims[T jwt.Claims](ctx context.Context) (T, bool) {
+	claims, ok := ctx.Value(jwtClaimsKey{}).(T)
+	return claims, ok
+}
+
+// verify checks the claims the parser leaves optional.
+func (c JWTConfig) verify(claims jwt.Claims) error {
+	if c.Audience != "" {
+		v, ok := claims.(interface{ VerifyAudience(string, bool) bool })
+		if !ok || !v.VerifyAudience(c.Audience, true) {
+			return errors.New("token has invalid audience")
+		}
+	}
+	if c.Issuer != "" {
+		v, ok := claims.(interface{ VerifyIssuer(string, bool) bool })
+		if !ok || !v.VerifyIssuer(c.Issuer, true) {
+			return errors.New("token has invalid issuer")
+		}
+	}
+	if c.RequireExpiry {
+		var valid bool
+		switch v := claims.(type) {
+		case interface{ VerifyExpiresAt(time.Time, bool) bool }:
+			valid = v.VerifyExpiresAt(time.Now(), true)
+		case interface{ VerifyExpiresAt(int64, bool) bool }:
+			valid = v.VerifyExpiresAt(time.Now().Unix(), true)
+		}
+		if !valid {
+			return errors.New("token has no valid expiry")
+		}
+	}
+	return nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	WriteError(w, r, errors.Wrap(errs.ErrorUnauthorized, err.Error()))
+}
@@ -0,0 +1,160 @@
+package httputils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type testClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	jwksData, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "rsa-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		}, {
+			// not supported, must not break the set
+			"kty": "OKP",
+			"kid": "ed-1",
+			"crv": "Ed25519",
+			"x":   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+		}},
+	})
+	if err := os.WriteFile(jwksFile, jwksData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(role string, expiresIn time.Duration) testClaims {
+		return testClaims{
+			Role: role,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "codesample",
+				Audience:  jwt.ClaimStrings{"api"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			},
+		}
+	}
+	sign := func(method jwt.SigningMethod, key interface{}, kid string, c testClaims) string {
+		token := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	config := JWTConfig{
+		Secret:        secret,
+		PublicKey:     &ecKey.PublicKey,
+		JWKSFile:      jwksFile,
+		Audience:      "api",
+		Issuer:        "codesample",
+		RequireExpiry: true,
+		NewClaims:     func() jwt.Claims { return &testClaims{} },
+		Authorize: func(r *http.Request, claims jwt.Claims) error {
+			if claims.(*testClaims).Role != "admin" {
+				return errors.New("admin role required")
+			}
+			return nil
+		},
+	}
+	var gotRole string
+	handler := JWT(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _ := JWTClaims[*testClaims](r.Context())
+		gotRole = c.Role
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	wrongAudience := claims("admin", time.Hour)
+	wrongAudience.Audience = jwt.ClaimStrings{"other"}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "HS256", token: sign(jwt.SigningMethodHS256, secret, "", claims("admin", time.Hour)), wantStatus: http.StatusOK},
+		{name: "RS256 from JWKS", token: sign(jwt.SigningMethodRS256, rsaKey, "rsa-1", claims("admin", time.Hour)), wantStatus: http.StatusOK},
+		{name: "ES256 public key", token: sign(jwt.SigningMethodES256, ecKey, "", claims("admin", time.Hour)), wantStatus: http.StatusOK},
+		{name: "missing token", token: "", wantStatus: http.StatusUnauthorized},
+		{name: "bad signature", token: sign(jwt.SigningMethodHS256, []byte("other"), "", claims("admin", time.Hour)), wantStatus: http.StatusUnauthorized},
+		{name: "unknown kid", token: sign(jwt.SigningMethodRS256, rsaKey, "rsa-2", claims("admin", time.Hour)), wantStatus: http.StatusUnauthorized},
+		{name: "expired", token: sign(jwt.SigningMethodHS256, secret, "", claims("admin", -time.Hour)), wantStatus: http.StatusUnauthorized},
+		{name: "wrong audience", token: sign(jwt.SigningMethodHS256, secret, "", wrongAudience), wantStatus: http.StatusUnauthorized},
+		{name: "not authorized", token: sign(jwt.SigningMethodHS256, secret, "", claims("user", time.Hour)), wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRole = ""
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && gotRole != "admin" {
+				t.Errorf("claims role = %q, want %q", gotRole, "admin")
+			}
+		})
+	}
+}
+
+func TestJWKSSharedFetch(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"keys":[{"kty":"EC","kid":"ec-1","crv":"P-256","x":"AQ","y":"Ag"}]}`))
+	}))
+	defer server.Close()
+
+	set := newJWKS("", server.URL, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := set.key("ec-1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+}
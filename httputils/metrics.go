@@ -0,0 +1,104 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/huylqbk/codesample/metrics"
+)
+
+const (
+	metricsPath = "/metrics"
+	// unmatchedRoute labels requests no route matched, so 404 and 405
+	// responses are counted without a label per requested path.
+	unmatchedRoute = "unmatched"
+)
+
+type routeMatchedKey struct{}
+
+var (
+	httpRequestsTotal = metrics.Factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled, by route template, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = metrics.Factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests, by route template, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	httpRequestsInFlight = metrics.Factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests being served, by route template and method.",
+	}, []string{"route", "method"})
+)
+
+// instrument records request metrics for the route template.
+func instrument(route, method string) Middleware {
+	inFlight := httpRequestsInFlight.WithLabelValues(route, method)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if matched, ok := r.Context().Value(routeMatchedKey{}).(*bool); ok {
+				*matched = true
+			}
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			status := strconv.Itoa(rw.Status())
+			httpRequestsTotal.WithLabelValues(route, method, status).Inc()
+			httpRequestDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// instrumentRoutes adds metrics to every route, labelled by prefix and path template.
+func instrumentRoutes(rs routes, prefix string) routes {
+	return rs.use(func(rt Route) Middleware {
+		return instrument(prefix+rt.Path, rt.Method)
+	})
+}
+
+// instrumentUnmatched records the requests that reach the router but no
+// instrumented route under route="unmatched".
+func instrumentUnmatched(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matched := new(bool)
+		start := time.Now()
+		rw := newResponseWriter(w)
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), routeMatchedKey{}, matched)))
+		if *matched {
+			return
+		}
+
+		method := r.Method
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+			http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		default:
+			method = "OTHER" // any token is a valid method; keep the label set bounded
+		}
+		status := strconv.Itoa(rw.Status())
+		httpRequestsTotal.WithLabelValues(unmatchedRoute, method, status).Inc()
+		httpRequestDuration.WithLabelValues(unmatchedRoute, method, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// mount serves h at path ahead of the router, outside prefix and middleware.
+func mount(path string, h, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == path {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
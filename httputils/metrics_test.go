@@ -0,0 +1,57 @@
+package httputils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAllowMetrics(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			prefix := "/" + name
+			r := newRouter("0").
+				AddPrefix(prefix).
+				AddPath("/users/{id}", "GET", func(c Context) error { return c.NoContent(http.StatusAccepted) }).
+				AllowMetrics()
+			addr, stop := start(t, r)
+			defer stop()
+
+			tests := []struct {
+				method string
+				path   string
+				route  string
+				status string
+			}{
+				{http.MethodGet, prefix + "/users/42", prefix + "/users/{id}", "202"},
+				{http.MethodGet, prefix + "/missing", unmatchedRoute, "404"},
+				{http.MethodDelete, prefix + "/users/42", unmatchedRoute, "405"},
+			}
+			for _, tt := range tests {
+				counter := httpRequestsTotal.WithLabelValues(tt.route, tt.method, tt.status)
+				before := testutil.ToFloat64(counter)
+
+				req, _ := http.NewRequest(tt.method, "http://"+addr+tt.path, nil)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+
+				if got := testutil.ToFloat64(counter) - before; got != 1 {
+					t.Errorf("%s %s: http_requests_total{route=%q,status=%q} grew by %v, want 1", tt.method, tt.path, tt.route, tt.status, got)
+				}
+			}
+
+			resp, err := http.Get("http://" + addr + "/metrics")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("/metrics status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+		})
+	}
+}
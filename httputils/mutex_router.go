@@ -1,42 +1,28 @@
 package httputils
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"log"
 	"net/http"
-	"os"
-	"os/signal"
-	"runtime"
-	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
-)
 
-type MuxHandle struct {
-	Method  string
-	Handler http.HandlerFunc
-}
+	"github.com/huylqbk/codesample/health"
+)
 
 type MuxRouter struct {
-	port        string
-	router      *mux.Router
-	prefix      string
-	middleware  []func(next http.Handler) http.Handler
-	handlers    map[string]MuxHandle
-	healthCheck bool
-	logRequest  bool
-	cors        bool
-	recovery    bool
+	port       string
+	router     *mux.Router
+	middleware []Middleware
+	options
 }
 
 func NewMuxRouter(port string) Router {
 	return &MuxRouter{
 		port:       port,
 		router:     mux.NewRouter(),
-		middleware: make([]func(next http.Handler) http.Handler, 0),
-		handlers:   make(map[string]MuxHandle, 0),
+		middleware: make([]Middleware, 0),
 	}
 }
 
@@ -46,6 +32,7 @@ func (r *MuxRouter) Default() {
 		AllowHealthCheck().
 		AllowLog().
 		AllowRecovery().
+		AllowRequestID().
 		ServeHTTP()
 }
 
@@ -59,8 +46,12 @@ func (r *MuxRouter) AllowRecovery() Router {
 	return r
 }
 
-func (r *MuxRouter) AllowLog() Router {
-	r.logRequest = true
+func (r *MuxRouter) AllowLog(config ...AccessLogConfig) Router {
+	var accessLog AccessLogConfig
+	if len(config) > 0 {
+		accessLog = config[0]
+	}
+	r.accessLog = &accessLog
 	return r
 }
 
@@ -69,115 +60,157 @@ func (r *MuxRouter) AllowHealthCheck() Router {
 	return r
 }
 
-func (r *MuxRouter) AllowCors() Router {
-	r.cors = true
+func (r *MuxRouter) AllowCors(config ...CorsConfig) Router {
+	cors := DefaultCorsConfig
+	if len(config) > 0 {
+		cors = config[0]
+	}
+	r.cors = &cors
 	return r
 }
 
-func (r *MuxRouter) ServeHTTP() {
-	r.router.PathPrefix(r.prefix)
-	// middleware
-	if r.logRequest {
-		r.AddMiddleware(r.logRequestlMiddleware)
-	}
-	if r.cors {
-		r.AddMiddleware(r.accessControlMiddleware)
-	}
+func (r *MuxRouter) AllowMetrics() Router {
+	r.metrics = true
+	return r
+}
 
-	for _, h := range r.middleware {
-		r.router.Use(h)
-	}
+func (r *MuxRouter) AllowRequestID() Router {
+	r.requestID = true
+	return r
+}
 
-	// handler
-	if r.healthCheck {
-		r.AddPath("/health", "GET", r.healthCheckHandler)
+func (r *MuxRouter) AllowOpenAPI(config ...OpenAPIConfig) Router {
+	openapi := DefaultOpenAPIConfig
+	if len(config) > 0 {
+		openapi = config[0]
 	}
+	r.openapi = &openapi
+	return r
+}
 
-	for p, h := range r.handlers {
-		r.router.HandleFunc(p, h.Handler).Methods(h.Method)
-	}
-	if r.recovery {
-		r.router.Use(r.recoverylMiddleware)
-	}
+func (r *MuxRouter) AllowAdmin(config AdminConfig) Router {
+	r.admin = &config
+	return r
+}
 
-	// server
-	server := http.Server{
-		Addr:         ":" + r.port,
-		Handler:      r.router,
-		WriteTimeout: 60 * time.Second,
-		ReadTimeout:  60 * time.Second,
-	}
-	errs := make(chan error)
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-		errs <- fmt.Errorf("%s", <-c)
-	}()
-	go func() {
-		log.Println("Server started on: " + r.port)
-		log.Fatal(server.ListenAndServe())
-	}()
+func (r *MuxRouter) Describe(path, method string, op Operation) Router {
+	r.docs.describe(path, method, op)
+	return r
+}
+
+func (r *MuxRouter) AddLivenessCheck(name string, checker health.Checker, config ...health.Config) Router {
+	r.addCheck(&r.liveness, name, checker, config)
+	return r
+}
+
+func (r *MuxRouter) AddReadinessCheck(name string, checker health.Checker, config ...health.Config) Router {
+	r.addCheck(&r.readiness, name, checker, config)
+	return r
+}
 
-	log.Println("exit", <-errs)
+func (r *MuxRouter) SetShutdownTimeout(timeout time.Duration) Router {
+	r.shutdownTimeout = timeout
+	return r
 }
 
-func (MuxRouter) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	MuxJson(w, 200, map[string]interface{}{
-		"message": "service is running",
-	})
+func (r *MuxRouter) SetTLS(config TLSConfig) Router {
+	r.tls = &config
+	return r
 }
 
-func (MuxRouter) logRequestlMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Println("request: " + r.RemoteAddr + ", method: " + r.Method + ", path: " + r.RequestURI)
-		next.ServeHTTP(w, r)
-	})
+func (r *MuxRouter) SetServerConfig(config ServerConfig) Router {
+	r.server = config
+	return r
 }
 
-func (MuxRouter) accessControlMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
+func (r *MuxRouter) OnBeforeShutdown(hook ShutdownHook) Router {
+	r.beforeShutdown = append(r.beforeShutdown, hook)
+	return r
+}
 
-		if r.Method == "OPTIONS" {
-			return
-		}
+func (r *MuxRouter) OnAfterShutdown(hook ShutdownHook) Router {
+	r.afterShutdown = append(r.afterShutdown, hook)
+	return r
+}
 
-		next.ServeHTTP(w, r)
-	})
+func (r *MuxRouter) ServeHTTP() {
+	serveUntilSignal(r)
 }
 
-func (MuxRouter) recoverylMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				buf := make([]byte, 2048)
-				n := runtime.Stack(buf, false)
-				buf = buf[:n]
+func (r *MuxRouter) Run(ctx context.Context) error {
+	handler, err := r.build()
+	if err != nil {
+		return err
+	}
 
-				errorMessage := fmt.Sprintf("recovering from err %v with %s", err, buf)
-				w.Write([]byte(`{"error":"+` + errorMessage + `"}`))
-			}
-		}()
+	return r.serve(ctx, r.httpServer(":"+r.port, handler))
+}
 
-		next.ServeHTTP(w, r)
-	})
+func (r *MuxRouter) Handler() (http.Handler, error) {
+	return r.build()
 }
 
-func (r *MuxRouter) AddPath(path, method string, handler http.HandlerFunc) *MuxRouter {
-	r.handlers[path] = MuxHandle{
-		Method:  method,
-		Handler: handler,
+// build assembles a fresh mux.Router from the registered middleware and routes.
+func (r *MuxRouter) build() (http.Handler, error) {
+	// middleware
+	middlewares := append([]Middleware{}, r.middleware...)
+	if r.recovery {
+		middlewares = append(middlewares, Recovery)
+	}
+
+	// handler
+	routes, err := r.buildRoutes()
+	if err != nil {
+		return nil, err
 	}
+
+	r.router = mux.NewRouter()
+	r.router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
+	r.router.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	router := r.router
+	if r.prefix != "" {
+		router = r.router.PathPrefix(r.prefix).Subrouter()
+	}
+	for _, h := range routes {
+		router.Handle(h.Path, h.handler(muxParams)).Methods(h.Method)
+	}
+	// gorilla runs Use middleware on matched routes only, so 404 and 405
+	// responses pass through the chain as on the other backends
+	var handler http.Handler = r.router
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return r.wrap(handler), nil
+}
+
+func (r *MuxRouter) AddPath(path, method string, handler HandlerFunc, middleware ...Middleware) Router {
+	r.routes.add(path, []string{method}, handler, middleware...)
+	return r
+}
+
+func (r *MuxRouter) AddMethods(path string, methods []string, handler HandlerFunc, middleware ...Middleware) Router {
+	r.routes.add(path, methods, handler, middleware...)
 	return r
 }
 
-func (r *MuxRouter) AddMiddleware(middleware func(next http.Handler) http.Handler) *MuxRouter {
+func (r *MuxRouter) AddMiddleware(middleware Middleware) Router {
 	r.middleware = append(r.middleware, middleware)
 	return r
 }
 
+func (r *MuxRouter) AddWebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig) Router {
+	r.routes.add(path, []string{"GET"}, r.webSocket(handler, config))
+	return r
+}
+
+func (r *MuxRouter) Group(prefix string, middleware ...Middleware) Group {
+	return r.group(prefix, middleware...)
+}
+
+func muxParams(r *http.Request, name string) string {
+	return mux.Vars(r)[name]
+}
+
 func MuxJson(w http.ResponseWriter, code int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -0,0 +1,325 @@
+package httputils
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	openAPIVersion = "3.1.0"
+
+	// swaggerUIVersion is the swagger-ui-dist release the UI page loads
+	// from the CDN when OpenAPIConfig.UIAssets is not set.
+	swaggerUIVersion = "5.17.14"
+)
+
+// OpenAPIConfig configures the generated OpenAPI document.
+type OpenAPIConfig struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+	// Path serves the JSON document; the YAML one is served next to it with
+	// a .yaml extension.
+	Path   string
+	UIPath string // serves a Swagger UI page for the document
+	// UIAssets holds swagger-ui.css and swagger-ui-bundle.js, such as an
+	// embed.FS of swagger-ui-dist, to serve them under UIPath instead of
+	// loading them from unpkg.com; set it for offline deployments.
+	UIAssets  fs.FS
+	DisableUI bool
+}
+
+var DefaultOpenAPIConfig = OpenAPIConfig{
+	Title:   "API",
+	Version: "1.0.0",
+	Path:    "/openapi.json",
+	UIPath:  "/docs",
+}
+
+// Operation documents a route registered with AddPath. Request is a value
+// of the type the handler passes to Context.Bind; its json fields describe
+// the body and its query and param fields the parameters. Responses maps a
+// status code to a value of the response type, or nil for an empty body.
+type Operation struct {
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	Request     interface{}
+	Responses   map[int]interface{}
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIComponents struct {
+	Schemas schemas `json:"schemas,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                    `json:"required"`
+	Content  map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]openAPIMedia `json:"content,omitempty"`
+}
+
+type openAPIMedia struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+// docs holds the Operation of each route, keyed by method and path.
+type docs map[string]Operation
+
+func (d *docs) describe(path, method string, op Operation) {
+	if *d == nil {
+		*d = make(docs)
+	}
+	(*d)[strings.ToUpper(method)+" "+path] = op
+}
+
+// openAPI builds the document for rs, mounted under prefix.
+func openAPI(config OpenAPIConfig, prefix string, rs routes, d docs) (*openAPIDocument, error) {
+	known := make(map[string]bool, len(rs))
+	for _, rt := range rs {
+		known[rt.Method+" "+rt.Path] = true
+	}
+	for key := range d {
+		if !known[key] {
+			return nil, fmt.Errorf("openapi: no route for documented %s", key)
+		}
+	}
+
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    openAPIInfo{Title: config.Title, Version: config.Version, Description: config.Description},
+		Paths:   map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			Schemas: schemas{},
+		},
+	}
+	for _, url := range config.Servers {
+		doc.Servers = append(doc.Servers, openAPIServer{URL: url})
+	}
+	problem := doc.Components.Schemas.of(reflect.TypeOf(Problem{}))
+
+	for _, rt := range rs {
+		p := pathParam.ReplaceAllString(prefix+rt.Path, "{$1}")
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[p][strings.ToLower(rt.Method)] = operation(doc.Components.Schemas, rt, p, d[rt.Method+" "+rt.Path], problem)
+	}
+	return doc, nil
+}
+
+func operation(s schemas, rt Route, p string, op Operation, problem *openAPISchema) *openAPIOperation {
+	o := &openAPIOperation{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Responses:   map[string]*openAPIResponse{},
+	}
+
+	var pathParams []openAPIParameter
+	if op.Request != nil {
+		t := indirectType(reflect.TypeOf(op.Request))
+		pathParams = s.parameters(t, "path")
+		o.Parameters = append(o.Parameters, s.parameters(t, "query")...)
+		if rt.Method != http.MethodGet && rt.Method != http.MethodHead && rt.Method != http.MethodDelete {
+			o.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  map[string]openAPIMedia{"application/json": {Schema: s.of(t)}},
+			}
+		}
+	}
+	// every template parameter must be declared, typed from Request when it has it
+	var params []openAPIParameter
+	for _, m := range pathParam.FindAllStringSubmatch(p, -1) {
+		param := openAPIParameter{Name: m[1], In: "path", Required: true, Schema: &openAPISchema{Type: "string"}}
+		for _, declared := range pathParams {
+			if declared.Name == m[1] {
+				param = declared
+			}
+		}
+		params = append(params, param)
+	}
+	o.Parameters = append(params, o.Parameters...)
+
+	if len(op.Responses) == 0 {
+		o.Responses["200"] = &openAPIResponse{Description: http.StatusText(http.StatusOK)}
+	}
+	for status, v := range op.Responses {
+		response := &openAPIResponse{Description: http.StatusText(status)}
+		if v != nil {
+			response.Content = map[string]openAPIMedia{"application/json": {Schema: s.of(reflect.TypeOf(v))}}
+		}
+		o.Responses[strconv.Itoa(status)] = response
+	}
+	o.Responses["default"] = &openAPIResponse{
+		Description: "Error",
+		Content:     map[string]openAPIMedia{ContentTypeProblem: {Schema: problem}},
+	}
+	return o
+}
+
+// addOpenAPIRoutes documents rs and serves the JSON and YAML documents and
+// the Swagger UI page.
+func (o *options) addOpenAPIRoutes(rs *routes) error {
+	config := *o.openapi
+	if config.Title == "" {
+		config.Title = DefaultOpenAPIConfig.Title
+	}
+	if config.Version == "" {
+		config.Version = DefaultOpenAPIConfig.Version
+	}
+	if config.Path == "" {
+		config.Path = DefaultOpenAPIConfig.Path
+	}
+	if config.UIPath == "" {
+		config.UIPath = DefaultOpenAPIConfig.UIPath
+	}
+	doc, err := openAPI(config, o.prefix, *rs, o.docs)
+	if err != nil {
+		return err
+	}
+	jsonDoc, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	yamlDoc, err := jsonToYAML(jsonDoc)
+	if err != nil {
+		return err
+	}
+
+	yamlPath := strings.TrimSuffix(config.Path, path.Ext(config.Path)) + ".yaml"
+	rs.add(config.Path, []string{"GET"}, func(c Context) error {
+		c.Response().Header().Set("Content-Type", "application/json")
+		_, err := c.Response().Write(jsonDoc)
+		return err
+	})
+	rs.add(yamlPath, []string{"GET"}, func(c Context) error {
+		c.Response().Header().Set("Content-Type", "application/yaml")
+		_, err := c.Response().Write(yamlDoc)
+		return err
+	})
+	if !config.DisableUI {
+		assets := "https://unpkg.com/swagger-ui-dist@" + swaggerUIVersion
+		if config.UIAssets != nil {
+			assets = o.prefix + config.UIPath
+			for _, name := range []string{"swagger-ui.css", "swagger-ui-bundle.js"} {
+				data, err := fs.ReadFile(config.UIAssets, name)
+				if err != nil {
+					return fmt.Errorf("openapi: UIAssets: %w", err)
+				}
+				contentType := "text/css; charset=utf-8"
+				if path.Ext(name) == ".js" {
+					contentType = "text/javascript; charset=utf-8"
+				}
+				rs.add(config.UIPath+"/"+name, []string{"GET"}, func(c Context) error {
+					c.Response().Header().Set("Content-Type", contentType)
+					_, err := c.Response().Write(data)
+					return err
+				})
+			}
+		}
+		var page strings.Builder
+		if err := swaggerUI.Execute(&page, swaggerUIPage{Spec: o.prefix + config.Path, Assets: assets}); err != nil {
+			return err
+		}
+		rs.add(config.UIPath, []string{"GET"}, func(c Context) error {
+			c.Response().Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, err := c.Response().Write([]byte(page.String()))
+			return err
+		})
+	}
+	return nil
+}
+
+// jsonToYAML re-encodes a JSON document as block-style YAML, keeping its key order.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	var reset func(n *yaml.Node)
+	reset = func(n *yaml.Node) {
+		n.Style = 0
+		for _, child := range n.Content {
+			reset(child)
+		}
+	}
+	reset(&node)
+	return yaml.Marshal(&node)
+}
+
+type swaggerUIPage struct {
+	Spec   string // URL of the document
+	Assets string // URL of the directory holding the Swagger UI assets
+}
+
+// swaggerUI loads the Swagger UI assets and points them at the document.
+var swaggerUI = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="{{.Assets}}/swagger-ui.css" crossorigin="anonymous" referrerpolicy="no-referrer">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets}}/swagger-ui-bundle.js" crossorigin="anonymous" referrerpolicy="no-referrer"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: {{.Spec}}, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`))
@@ -0,0 +1,216 @@
+package httputils
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huylqbk/codesample/validation"
+)
+
+// openAPISchema is the subset of JSON Schema generated from Go types.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	MinLength            *float64                  `json:"minLength,omitempty"`
+	MaxLength            *float64                  `json:"maxLength,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	MinItems             *float64                  `json:"minItems,omitempty"`
+	MaxItems             *float64                  `json:"maxItems,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	schemaNameRe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	// typeArgPkgRe matches the package of a type argument, as in Page[example.com/app.User]
+	typeArgPkgRe = regexp.MustCompile(`[A-Za-z0-9_.-]*/|[A-Za-z0-9_]+\.`)
+)
+
+// schemas builds schemas for Go types; named structs are collected as
+// components and referenced by name.
+type schemas map[string]*openAPISchema
+
+func (s schemas) of(t reflect.Type) *openAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &openAPISchema{Type: "string", Format: "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &openAPISchema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := typeArgPkgRe.ReplaceAllString(t.Name(), "")
+		name = strings.TrimSuffix(schemaNameRe.ReplaceAllString(name, "_"), "_")
+		if _, ok := s[name]; !ok {
+			s[name] = nil // placeholder for recursive types
+			s[name] = s.object(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	return &openAPISchema{}
+}
+
+// object describes the JSON body of struct t. Fields bound only from the
+// query string or path are left out.
+func (s schemas) object(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	s.addFields(schema, t)
+	return schema
+}
+
+func (s schemas) addFields(schema *openAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" || (tag == "" && (f.Tag.Get("query") != "" || f.Tag.Get("param") != "")) {
+			continue
+		}
+		if f.Anonymous && name == "" && indirectType(f.Type).Kind() == reflect.Struct {
+			s.addFields(schema, indirectType(f.Type))
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := s.of(f.Type)
+		if constrain(prop, f) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+}
+
+// parameters describes the fields of t bound from in, "query" or "path".
+func (s schemas) parameters(t reflect.Type, in string) []openAPIParameter {
+	key := in
+	if in == "path" {
+		key = "param"
+	}
+	var params []openAPIParameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct {
+			params = append(params, s.parameters(indirectType(f.Type), in)...)
+			continue
+		}
+		name := strings.Split(f.Tag.Get(key), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := s.of(f.Type)
+		required := constrain(schema, f)
+		params = append(params, openAPIParameter{
+			Name:     name,
+			In:       in,
+			Required: required || in == "path",
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+// constrain copies the validate rules of f onto schema and reports whether
+// the field is required.
+func constrain(schema *openAPISchema, f reflect.StructField) bool {
+	if schema.Ref != "" {
+		return hasRule(f, "required")
+	}
+	var required bool
+	for _, rule := range validation.ParseTag(f.Tag.Get("validate")) {
+		switch rule.Name {
+		case "required":
+			required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(rule.Arg, 64)
+			if err != nil {
+				continue
+			}
+			min := rule.Name == "min"
+			switch schema.Type {
+			case "string":
+				setBound(&schema.MinLength, &schema.MaxLength, min, n)
+			case "array", "object":
+				setBound(&schema.MinItems, &schema.MaxItems, min, n)
+			case "integer", "number":
+				setBound(&schema.Minimum, &schema.Maximum, min, n)
+			}
+		case "email":
+			schema.Format = "email"
+		case "oneof":
+			for _, o := range strings.Fields(rule.Arg) {
+				if n, err := strconv.ParseFloat(o, 64); err == nil && schema.Type != "string" {
+					schema.Enum = append(schema.Enum, n)
+				} else {
+					schema.Enum = append(schema.Enum, o)
+				}
+			}
+		case "regex":
+			schema.Pattern = rule.Arg
+		}
+	}
+	return required
+}
+
+func hasRule(f reflect.StructField, name string) bool {
+	for _, rule := range validation.ParseTag(f.Tag.Get("validate")) {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func setBound(min, max **float64, isMin bool, n float64) {
+	if isMin {
+		*min = &n
+	} else {
+		*max = &n
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
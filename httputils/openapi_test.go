@@ -0,0 +1,131 @@
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type createUser struct {
+	Org   string `param:"org"`
+	Dry   bool   `query:"dry_run"`
+	Name  string `json:"name" validate:"required,min=2"`
+	Role  string `json:"role" validate:"oneof=admin user"`
+	Email string `json:"email" validate:"email"`
+}
+
+type userResponse struct {
+	ID      int64          `json:"id"`
+	Name    string         `json:"name"`
+	Friends []userResponse `json:"friends,omitempty"`
+}
+
+func TestAllowOpenAPI(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			noop := func(c Context) error { return nil }
+			r := newRouter("0").
+				AddPrefix("/api").
+				AllowOpenAPI(OpenAPIConfig{Title: "Users"}).
+				AddPath("/orgs/{org}/users", "POST", noop).
+				AddPath("/users/{id}", "GET", noop).
+				Describe("/orgs/{org}/users", "POST", Operation{
+					Summary:   "Create a user",
+					Request:   createUser{},
+					Responses: map[int]interface{}{http.StatusCreated: userResponse{}},
+				})
+			addr, stop := start(t, r)
+			defer stop()
+
+			resp, err := http.Get("http://" + addr + "/api/openapi.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc openAPIDocument
+			err = json.NewDecoder(resp.Body).Decode(&doc)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if doc.OpenAPI != openAPIVersion || doc.Info.Title != "Users" {
+				t.Errorf("openapi = %q, title = %q", doc.OpenAPI, doc.Info.Title)
+			}
+			op := doc.Paths["/api/orgs/{org}/users"]["post"]
+			if op == nil {
+				t.Fatalf("paths = %v, want POST /api/orgs/{org}/users", doc.Paths)
+			}
+			if len(op.Parameters) != 2 || op.Parameters[0].In != "path" || op.Parameters[1].Name != "dry_run" {
+				t.Errorf("parameters = %+v, want path org and query dry_run", op.Parameters)
+			}
+			body := op.RequestBody.Content["application/json"].Schema
+			if body.Ref != "#/components/schemas/createUser" {
+				t.Errorf("request schema = %+v", body)
+			}
+			schema := doc.Components.Schemas["createUser"]
+			if len(schema.Properties) != 3 || len(schema.Required) != 1 || schema.Properties["email"].Format != "email" || len(schema.Properties["role"].Enum) != 2 {
+				t.Errorf("createUser schema = %+v", schema)
+			}
+			if op.Responses["201"] == nil || op.Responses["default"] == nil {
+				t.Errorf("responses = %v, want 201 and default", op.Responses)
+			}
+			if friends := doc.Components.Schemas["userResponse"].Properties["friends"]; friends.Items.Ref != "#/components/schemas/userResponse" {
+				t.Errorf("recursive schema = %+v", friends)
+			}
+			if get := doc.Paths["/api/users/{id}"]["get"]; get == nil || len(get.Parameters) != 1 || get.Responses["200"] == nil {
+				t.Errorf("undocumented route = %+v", get)
+			}
+
+			for path, want := range map[string]string{"/api/openapi.yaml": "openapi: 3.1.0", "/api/docs": "/api/openapi.json"} {
+				resp, err := http.Get("http://" + addr + path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if !strings.Contains(string(data), want) {
+					t.Errorf("GET %s does not contain %q:\n%s", path, want, data)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAPIUnknownRoute(t *testing.T) {
+	r := NewChiRouter("0").AllowOpenAPI().Describe("/missing", "GET", Operation{})
+	if err := r.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "GET /missing") {
+		t.Errorf("Run() error = %v, want unknown route", err)
+	}
+}
+
+func TestOpenAPIUIAssets(t *testing.T) {
+	assets := fstest.MapFS{
+		"swagger-ui.css":       {Data: []byte("body {}")},
+		"swagger-ui-bundle.js": {Data: []byte("var SwaggerUIBundle;")},
+	}
+	handler, err := NewMuxRouter("0").AddPrefix("/api").AllowOpenAPI(OpenAPIConfig{UIAssets: assets}).Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"/api/docs":                      `src="/api/docs/swagger-ui-bundle.js"`,
+		"/api/docs/swagger-ui.css":       "body {}",
+		"/api/docs/swagger-ui-bundle.js": "var SwaggerUIBundle;",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if body := rec.Body.String(); !strings.Contains(body, want) || strings.Contains(body, "unpkg.com") {
+			t.Errorf("GET %s = %d %q, want %q", path, rec.Code, body, want)
+		}
+	}
+
+	delete(assets, "swagger-ui.css")
+	if _, err := NewMuxRouter("0").AllowOpenAPI(OpenAPIConfig{UIAssets: assets}).Handler(); err == nil {
+		t.Error("Handler() with a missing asset: err = nil")
+	}
+}
@@ -0,0 +1,102 @@
+package httputils
+
+import (
+	"net/http"
+
+	"github.com/huylqbk/codesample/health"
+	"github.com/huylqbk/codesample/metrics"
+)
+
+// options holds the settings every Router backend shares.
+type options struct {
+	prefix      string
+	routes      routes
+	healthCheck bool
+	accessLog   *AccessLogConfig
+	cors        *CorsConfig
+	recovery    bool
+	metrics     bool
+	requestID   bool
+	liveness    *health.Registry
+	readiness   *health.Registry
+	openapi     *OpenAPIConfig
+	docs        docs
+	admin       *AdminConfig
+	lifecycle
+}
+
+// buildRoutes returns the registered routes plus the built-in ones,
+// validated and ready to register on a backend.
+func (o *options) buildRoutes() (routes, error) {
+	rs := o.routes.grouped()
+	if o.openapi != nil {
+		if err := o.addOpenAPIRoutes(&rs); err != nil {
+			return nil, err
+		}
+	}
+	if o.admin != nil {
+		if err := o.admin.checkAuth(); err != nil {
+			return nil, err
+		}
+		if o.admin.Addr == "" {
+			o.addAdminRoutes(&rs)
+		}
+	}
+	if o.healthCheck {
+		o.addHealthRoutes(&rs)
+	}
+	if err := rs.validate(); err != nil {
+		return nil, err
+	}
+	if o.metrics {
+		rs = instrumentRoutes(rs, o.prefix)
+	}
+	if o.accessLog != nil {
+		rs = rs.use(func(rt Route) Middleware {
+			return logRoute(o.prefix + rt.Path)
+		})
+	}
+	return rs, nil
+}
+
+// wrap applies the policies that run ahead of routing on every backend.
+func (o *options) wrap(h http.Handler) http.Handler {
+	if o.metrics {
+		h = instrumentUnmatched(h)
+	}
+	if o.server.MaxBodyBytes > 0 {
+		h = MaxBodySize(o.server.MaxBodyBytes)(h)
+	}
+	if o.cors != nil {
+		h = Cors(*o.cors)(h)
+	}
+	if o.accessLog != nil {
+		h = accessLog(*o.accessLog, o.prefix)(h)
+	}
+	if o.requestID {
+		h = RequestID(h)
+	}
+	if o.metrics {
+		h = mount(metricsPath, metrics.Handler(), h)
+	}
+	return h
+}
+
+func (o *options) addCheck(registry **health.Registry, name string, checker health.Checker, config []health.Config) {
+	if *registry == nil {
+		*registry = health.NewRegistry()
+	}
+	(*registry).Register(name, checker, config...)
+}
+
+// addHealthRoutes serves /livez and /readyz; /health is kept as an alias of
+// /readyz. Readiness fails while the server drains.
+func (o *options) addHealthRoutes(rs *routes) {
+	o.addCheck(&o.readiness, "server", health.CheckerFunc(o.serving), nil)
+	if o.liveness == nil {
+		o.liveness = health.NewRegistry()
+	}
+	rs.add("/health", []string{"GET"}, WrapHandler(o.readiness))
+	rs.add("/livez", []string{"GET"}, WrapHandler(o.liveness))
+	rs.add("/readyz", []string{"GET"}, WrapHandler(o.readiness))
+}
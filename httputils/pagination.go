@@ -0,0 +1,336 @@
+package httputils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/huylqbk/codesample/database"
+	"github.com/huylqbk/codesample/errs"
+)
+
+const (
+	HeaderTotalCount = "X-Total-Count"
+
+	defaultPageSize    = 20
+	defaultMaxPageSize = 100
+	defaultCursorField = "id"
+)
+
+// PaginationConfig sets the limits of one list endpoint.
+type PaginationConfig struct {
+	DefaultSize int // defaults to 20
+	MaxSize     int // larger sizes are lowered to it, defaults to 100
+	// SortFields are the columns clients may sort by; sorting is refused
+	// when empty.
+	SortFields  []string
+	DefaultSort string // such as "-created_at"
+	// CursorField is the unique column cursor pagination walks, defaults to "id".
+	CursorField string
+}
+
+// SortField orders by one column.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Pagination is the page a list request asks for. Offset pagination reads
+// `page` and `size`; a `cursor` or `limit` parameter switches to cursor
+// pagination, which continues after the cursor of the previous page and may
+// only sort by the cursor field. `sort` lists fields, each prefixed with "-"
+// for descending order:
+//
+//	GET /orders?page=2&size=50&sort=-created_at,id
+//	GET /orders?limit=50&cursor=eyJpZCI6NTB9
+type Pagination struct {
+	Page   int // from 1, offset pagination only
+	Size   int
+	Cursor string
+	Sort   []SortField
+
+	cursorMode  bool
+	cursorField string
+	after       interface{} // the decoded Cursor
+}
+
+// ParsePagination reads the pagination parameters of r. Invalid values are
+// returned as errs.FieldErrors.
+func ParsePagination(r *http.Request, config ...PaginationConfig) (Pagination, error) {
+	var c PaginationConfig
+	if len(config) > 0 {
+		c = config[0]
+	}
+	if c.DefaultSize <= 0 {
+		c.DefaultSize = defaultPageSize
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = defaultMaxPageSize
+	}
+	if c.CursorField == "" {
+		c.CursorField = defaultCursorField
+	}
+
+	query := r.URL.Query()
+	p := Pagination{Page: 1, Size: c.DefaultSize, cursorField: c.CursorField}
+	p.cursorMode = query.Has("cursor") || query.Has("limit")
+	var fields errs.FieldErrors
+	positive := func(name string, v *int) {
+		s := query.Get(name)
+		if s == "" {
+			return
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			fields = append(fields, errs.FieldError{Field: name, Message: "must be a positive integer"})
+			return
+		}
+		*v = n
+	}
+
+	if p.cursorMode {
+		positive("limit", &p.Size)
+		p.Cursor = query.Get("cursor")
+		if p.Cursor != "" {
+			after, err := decodeCursor(p.Cursor)
+			if err != nil {
+				fields = append(fields, errs.FieldError{Field: "cursor", Message: "is invalid"})
+			}
+			p.after = after
+		}
+	} else {
+		positive("page", &p.Page)
+		positive("size", &p.Size)
+	}
+	if p.Size > c.MaxSize {
+		p.Size = c.MaxSize
+	}
+
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = c.DefaultSort
+	}
+	for _, s := range strings.Split(sort, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		f := SortField{Field: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+		switch {
+		case p.cursorMode && f.Field != c.CursorField:
+			fields = append(fields, errs.FieldError{Field: "sort", Message: "must be " + c.CursorField + " with a cursor"})
+		case !p.cursorMode && !c.sortable(f.Field):
+			fields = append(fields, errs.FieldError{Field: "sort", Message: "cannot sort by " + f.Field})
+		default:
+			p.Sort = append(p.Sort, f)
+		}
+	}
+	if p.cursorMode && len(p.Sort) == 0 {
+		p.Sort = []SortField{{Field: c.CursorField}}
+	}
+
+	if len(fields) > 0 {
+		return p, fields
+	}
+	return p, nil
+}
+
+// Offset is the number of items before the page.
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.Size
+}
+
+// Apply orders and limits tx to the page. Cursor pagination fetches one item
+// more than Size to learn whether another page follows.
+func (p Pagination) Apply(tx *gorm.DB) *gorm.DB {
+	for _, s := range p.Sort {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: s.Field}, Desc: s.Desc})
+	}
+	if !p.cursorMode {
+		return tx.Offset(p.Offset()).Limit(p.Size)
+	}
+	if p.after != nil {
+		column := clause.Column{Name: p.cursorField}
+		if p.Sort[0].Desc {
+			tx = tx.Where(clause.Lt{Column: column, Value: p.after})
+		} else {
+			tx = tx.Where(clause.Gt{Column: column, Value: p.after})
+		}
+	}
+	return tx.Limit(p.Size + 1)
+}
+
+// Page is a list response envelope. Write it to also send the Link and
+// X-Total-Count headers.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	Size       int    `json:"size"`
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	pagination Pagination
+}
+
+// NewPage wraps items fetched with offset pagination from another source
+// than gorm.
+func NewPage[T any](items []T, total int64, p Pagination) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return Page[T]{Items: items, Total: total, Page: p.Page, Size: p.Size, pagination: p}
+}
+
+// Paginate counts the records of tx and fetches the page of them with
+// database.GetAll.
+func Paginate[T comparable](tx *gorm.DB, p Pagination) (Page[T], error) {
+	var model T
+	var total int64
+	if err := tx.Session(&gorm.Session{}).Model(&model).Count(&total).Error; err != nil {
+		return Page[T]{}, err
+	}
+	items, err := database.GetAll[T](p.Apply(tx.Session(&gorm.Session{})))
+	if err != nil {
+		return Page[T]{}, err
+	}
+	if !p.cursorMode {
+		return NewPage(items, total, p), nil
+	}
+
+	page := Page[T]{Items: items, Total: total, Size: p.Size, pagination: p}
+	if len(items) > p.Size {
+		page.Items = items[:p.Size]
+		after, err := fieldValue(tx, page.Items[p.Size-1], p.cursorField)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		if page.NextCursor, err = encodeCursor(after); err != nil {
+			return Page[T]{}, err
+		}
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page, nil
+}
+
+// Write sends the page as JSON with a Link header to its neighbours and
+// X-Total-Count.
+func (p Page[T]) Write(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set(HeaderTotalCount, strconv.FormatInt(p.Total, 10))
+	if links := p.links(r.URL); len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	writeJSON(w, http.StatusOK, p)
+	return nil
+}
+
+// links returns RFC 8288 links relative to u.
+func (p Page[T]) links(u *url.URL) []string {
+	link := func(rel string, set map[string]string) string {
+		query := u.Query()
+		for k, v := range set {
+			query.Set(k, v)
+		}
+		ref := url.URL{Path: u.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", ref.String(), rel)
+	}
+
+	if p.Size <= 0 {
+		return nil
+	}
+	if p.pagination.cursorMode {
+		if p.NextCursor == "" {
+			return nil
+		}
+		return []string{link("next", map[string]string{"cursor": p.NextCursor, "limit": strconv.Itoa(p.Size)})}
+	}
+
+	size := strconv.Itoa(p.Size)
+	last := int((p.Total + int64(p.Size) - 1) / int64(p.Size))
+	if last < 1 {
+		last = 1
+	}
+	page := func(n int) map[string]string {
+		return map[string]string{"page": strconv.Itoa(n), "size": size}
+	}
+	links := []string{link("first", page(1))}
+	if p.Page > 1 {
+		prev := p.Page - 1
+		if prev > last {
+			prev = last
+		}
+		links = append(links, link("prev", page(prev)))
+	}
+	if p.Page < last {
+		links = append(links, link("next", page(p.Page+1)))
+	}
+	return append(links, link("last", page(last)))
+}
+
+func encodeCursor(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string) (interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	// the value ends up in a condition, so only scalars are accepted
+	switch v := v.(type) {
+	case json.Number:
+		return v.String(), nil
+	case string:
+		return v, nil
+	default:
+		return nil, errs.ErrorInvalidRequest
+	}
+}
+
+var gormSchemas sync.Map
+
+// fieldValue returns the value of item's field stored in column.
+func fieldValue(tx *gorm.DB, item interface{}, column string) (interface{}, error) {
+	s, err := schema.Parse(item, &gormSchemas, tx.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	field := s.LookUpField(column)
+	if field == nil {
+		return nil, fmt.Errorf("httputils: %s has no field %s", s.Name, column)
+	}
+	v, _ := field.ValueOf(tx.Statement.Context, reflect.Indirect(reflect.ValueOf(item)))
+	return v, nil
+}
+
+// sortable reports whether clients may sort by field.
+func (c PaginationConfig) sortable(field string) bool {
+	for _, f := range c.SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
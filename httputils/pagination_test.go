@@ -0,0 +1,210 @@
+package httputils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+type paginationOrder struct {
+	ID        int
+	CreatedAt int64
+}
+
+// dryRunDB builds SQL without a database.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestParsePagination(t *testing.T) {
+	config := PaginationConfig{MaxSize: 50, SortFields: []string{"id", "created_at"}, DefaultSort: "-created_at"}
+	cursor, _ := encodeCursor(10)
+	objectCursor, _ := encodeCursor(map[string]int{"id": 10})
+	arrayCursor, _ := encodeCursor([]int{10})
+	tests := []struct {
+		name       string
+		query      string
+		want       Pagination
+		wantFields []string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  Pagination{Page: 1, Size: 20, Sort: []SortField{{Field: "created_at", Desc: true}}},
+		},
+		{
+			name:  "page and sort",
+			query: "page=3&size=10&sort=id,-created_at",
+			want:  Pagination{Page: 3, Size: 10, Sort: []SortField{{Field: "id"}, {Field: "created_at", Desc: true}}},
+		},
+		{
+			name:  "size over max",
+			query: "size=1000",
+			want:  Pagination{Page: 1, Size: 50, Sort: []SortField{{Field: "created_at", Desc: true}}},
+		},
+		{
+			name:  "cursor",
+			query: "limit=5&cursor=" + cursor + "&sort=-id",
+			want:  Pagination{Page: 1, Size: 5, Cursor: cursor, Sort: []SortField{{Field: "id", Desc: true}}},
+		},
+		{
+			name:       "invalid",
+			query:      "page=0&size=x&sort=password",
+			wantFields: []string{"page", "size", "sort"},
+		},
+		{
+			name:       "invalid cursor",
+			query:      "cursor=%21%21&sort=created_at",
+			wantFields: []string{"cursor", "sort"},
+		},
+		{
+			name:       "object cursor",
+			query:      "cursor=" + objectCursor + "&sort=id",
+			wantFields: []string{"cursor"},
+		},
+		{
+			name:       "array cursor",
+			query:      "cursor=" + arrayCursor + "&sort=id",
+			wantFields: []string{"cursor"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePagination(httptest.NewRequest(http.MethodGet, "/orders?"+tt.query, nil), config)
+			if tt.wantFields != nil {
+				var got []string
+				var fields errs.FieldErrors
+				errors.As(err, &fields)
+				for _, f := range fields {
+					got = append(got, f.Field)
+				}
+				if !reflect.DeepEqual(got, tt.wantFields) {
+					t.Errorf("invalid fields = %v, want %v (%v)", got, tt.wantFields, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Page != tt.want.Page || p.Size != tt.want.Size || p.Cursor != tt.want.Cursor || !reflect.DeepEqual(p.Sort, tt.want.Sort) {
+				t.Errorf("pagination = %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginationApply(t *testing.T) {
+	db := dryRunDB(t)
+	cursor, _ := encodeCursor(10)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "page=3&size=10&sort=-created_at",
+			want:  `ORDER BY "created_at" DESC LIMIT 10 OFFSET 20`,
+		},
+		{
+			query: "limit=5&cursor=" + cursor + "&sort=-id",
+			want:  `WHERE "id" < $1 ORDER BY "id" DESC LIMIT 6`,
+		},
+	}
+	for _, tt := range tests {
+		p, err := ParsePagination(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil), PaginationConfig{SortFields: []string{"created_at"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmt := p.Apply(db.Model(&paginationOrder{})).Find(&[]paginationOrder{}).Statement
+		if sql := stmt.SQL.String(); !strings.HasSuffix(sql, tt.want) {
+			t.Errorf("%s: SQL = %s, want suffix %s", tt.query, sql, tt.want)
+		}
+	}
+}
+
+func TestPageWrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		page      func(p Pagination) Page[int]
+		wantLinks []string
+	}{
+		{
+			name:   "middle page",
+			target: "/orders?page=2&size=2&status=open",
+			page: func(p Pagination) Page[int] {
+				return NewPage([]int{3, 4}, 5, p)
+			},
+			wantLinks: []string{
+				`</orders?page=1&size=2&status=open>; rel="first"`,
+				`</orders?page=1&size=2&status=open>; rel="prev"`,
+				`</orders?page=3&size=2&status=open>; rel="next"`,
+				`</orders?page=3&size=2&status=open>; rel="last"`,
+			},
+		},
+		{
+			name:   "cursor",
+			target: "/orders?limit=2",
+			page: func(p Pagination) Page[int] {
+				return Page[int]{Items: []int{1, 2}, Total: 5, Size: 2, NextCursor: "Mg", pagination: p}
+			},
+			wantLinks: []string{`</orders?cursor=Mg&limit=2>; rel="next"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			p, err := ParsePagination(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			tt.page(p).Write(rec, req)
+
+			if got := rec.Header().Get("Link"); got != strings.Join(tt.wantLinks, ", ") {
+				t.Errorf("Link = %s\nwant %s", got, strings.Join(tt.wantLinks, ", "))
+			}
+			if got := rec.Header().Get(HeaderTotalCount); got != "5" {
+				t.Errorf("%s = %q, want 5", HeaderTotalCount, got)
+			}
+			if !strings.Contains(rec.Body.String(), `"total":5`) {
+				t.Errorf("body = %s, want the envelope", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	db := dryRunDB(t)
+	v, err := fieldValue(db, paginationOrder{ID: 42}, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, err := encodeCursor(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil || after != "42" {
+		t.Errorf("decodeCursor = %v, %v, want 42", after, err)
+	}
+	object, _ := encodeCursor(map[string]int{"id": 42})
+	if _, err := decodeCursor(object); errors.Cause(err) != errs.ErrorInvalidRequest {
+		t.Errorf("decodeCursor of an object: err = %v, want %v", err, errs.ErrorInvalidRequest)
+	}
+	if _, err := fieldValue(db, paginationOrder{}, "missing"); err == nil {
+		t.Error("fieldValue of a missing column: err = nil")
+	}
+}
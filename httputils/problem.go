@@ -0,0 +1,104 @@
+package httputils
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime"
+
+	"github.com/pkg/errors"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+const ContentTypeProblem = "application/problem+json"
+
+// ProblemTypeBase prefixes the errs.ToType identifier to form the problem type URI.
+var ProblemTypeBase = "urn:codesample:problem:"
+
+// Problem is an RFC 7807 error response.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    []errs.FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem describes err for r. The status comes from errs.ToCode; errors
+// that are not errs sentinels are logged and reported without detail so
+// internals do not leak.
+func NewProblem(r *http.Request, err error) Problem {
+	status := errs.ToCode(err)
+	p := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Instance:  r.URL.Path,
+		RequestID: GetRequestID(r.Context()),
+	}
+	if kind := errs.ToType(err); kind != "" {
+		p.Type = ProblemTypeBase + kind
+		p.Detail = err.Error()
+	} else {
+		log.Println("error:", err)
+	}
+	var fields errs.FieldErrors
+	if errors.As(err, &fields) {
+		p.Errors = fields
+	}
+	return p
+}
+
+// newStatusProblem describes a bare status code raised by a backend.
+func newStatusProblem(r *http.Request, status int) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Instance:  r.URL.Path,
+		RequestID: GetRequestID(r.Context()),
+	}
+}
+
+func (p Problem) Write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// WriteError writes err as application/problem+json.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	NewProblem(r, err).Write(w)
+}
+
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, errs.ErrorNotFound)
+}
+
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, errs.ErrorMethodNotAllowed)
+}
+
+// Recovery turns a panic into a 500 problem response and logs its stack.
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			buf := make([]byte, 2048)
+			n := runtime.Stack(buf, false)
+			log.Printf("recovering from err %v with %s", err, buf[:n])
+			WriteError(w, r, errs.ErrorServerFailure)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,123 @@
+package httputils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+// multiError is an error type that cannot be used as a map key.
+type multiError []error
+
+func (m multiError) Error() string { return fmt.Sprint([]error(m)) }
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantType   string
+		wantStatus int
+		wantDetail string
+		wantFields int
+	}{
+		{
+			name:       "known error",
+			err:        errors.Wrap(errs.ErrorForbidden, "admin role required"),
+			wantType:   ProblemTypeBase + "forbidden",
+			wantStatus: http.StatusForbidden,
+			wantDetail: "admin role required: " + errs.ErrorForbidden.Error(),
+		},
+		{
+			name:       "field errors",
+			err:        errs.FieldErrors{{Field: "email", Message: "is required"}},
+			wantType:   ProblemTypeBase + "invalid-request",
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "invalid request: email is required",
+			wantFields: 1,
+		},
+		{
+			name:       "unknown error hides detail",
+			err:        errors.New("dial tcp 10.0.0.1:5432: connection refused"),
+			wantType:   "about:blank",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "unhashable error",
+			err:        errors.Wrap(multiError{errors.New("a"), errors.New("b")}, "batch"),
+			wantType:   "about:blank",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			p := NewProblem(r, tt.err)
+			if p.Type != tt.wantType || p.Status != tt.wantStatus || p.Detail != tt.wantDetail {
+				t.Errorf("NewProblem() = %+v, want type %q status %d detail %q", p, tt.wantType, tt.wantStatus, tt.wantDetail)
+			}
+			if p.Instance != "/users/1" {
+				t.Errorf("instance = %q, want %q", p.Instance, "/users/1")
+			}
+			if len(p.Errors) != tt.wantFields {
+				t.Errorf("errors = %v, want %d", p.Errors, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").
+				AllowRecovery().
+				AllowRequestID().
+				AddPath("/users/{id}", "GET", func(c Context) error {
+					return errs.ErrorResourceNotFound
+				}).
+				AddPath("/panic", "GET", func(c Context) error {
+					panic("boom")
+				})
+			addr, stop := start(t, r)
+			defer stop()
+
+			tests := []struct {
+				method     string
+				path       string
+				wantStatus int
+			}{
+				{http.MethodGet, "/users/1", http.StatusBadRequest},
+				{http.MethodGet, "/missing", http.StatusNotFound},
+				{http.MethodDelete, "/users/1", http.StatusMethodNotAllowed},
+				{http.MethodGet, "/panic", http.StatusInternalServerError},
+			}
+			for _, tt := range tests {
+				req, _ := http.NewRequest(tt.method, "http://"+addr+tt.path, nil)
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var p Problem
+				err = json.NewDecoder(resp.Body).Decode(&p)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatalf("%s %s: decode problem: %v", tt.method, tt.path, err)
+				}
+				if resp.StatusCode != tt.wantStatus || p.Status != tt.wantStatus {
+					t.Errorf("%s %s: status = %d (problem %d), want %d", tt.method, tt.path, resp.StatusCode, p.Status, tt.wantStatus)
+				}
+				if ct := resp.Header.Get("Content-Type"); ct != ContentTypeProblem {
+					t.Errorf("%s %s: content type = %q, want %q", tt.method, tt.path, ct, ContentTypeProblem)
+				}
+				if p.RequestID == "" || p.RequestID != resp.Header.Get(HeaderRequestID) {
+					t.Errorf("%s %s: request_id = %q, want %q", tt.method, tt.path, p.RequestID, resp.Header.Get(HeaderRequestID))
+				}
+			}
+		})
+	}
+}
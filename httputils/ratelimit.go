@@ -0,0 +1,341 @@
+package httputils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+const (
+	TokenBucket   = "token_bucket"
+	SlidingWindow = "sliding_window"
+
+	rateLimitSweepInterval = time.Minute
+)
+
+// Rate allows Requests per Window for each key.
+type Rate struct {
+	Algorithm string // TokenBucket (default) or SlidingWindow
+	Requests  int
+	Window    time.Duration
+	Burst     int // token bucket capacity, defaults to Requests
+}
+
+func (r Rate) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Requests
+}
+
+func (r Rate) String() string {
+	algorithm := r.Algorithm
+	if algorithm == "" {
+		algorithm = TokenBucket
+	}
+	return fmt.Sprintf("%s:%d/%s:%d", algorithm, r.Requests, r.Window, r.burst())
+}
+
+// RateLimitResult is the state of a key after one request was counted.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration // until the full limit is available again
+	RetryAfter time.Duration // until the next request is allowed, when denied
+}
+
+// RateLimitStore keeps limiter state. The default keeps it in memory; implement
+// it over a shared store such as Redis to enforce one limit across instances.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rate Rate) (RateLimitResult, error)
+}
+
+type RateLimitConfig struct {
+	Rate    Rate
+	KeyFunc func(r *http.Request) string // defaults to KeyByIP, or KeyByForwardedIP with TrustedProxies
+	Store   RateLimitStore               // defaults to NewMemoryRateLimitStore
+	// TrustedProxies lists the IPs or CIDR ranges of the load balancers in
+	// front of the server; requests through them are limited by the client
+	// IP in X-Forwarded-For. Ignored when KeyFunc is set.
+	TrustedProxies []string
+	// Name prefixes the keys in Store, defaults to the Rate, so limiters
+	// with different rates that share a Store keep separate counters. Give
+	// limiters the same Name to share one limit.
+	Name string
+}
+
+// RateLimit rejects requests over config.Rate with 429, Retry-After and
+// RateLimit-* headers. Add it with AddMiddleware for a global limit or pass
+// it to AddPath for a single route; every call keeps its own counters
+// unless they share a Store.
+func RateLimit(config RateLimitConfig) Middleware {
+	rate := config.Rate
+	if rate.Requests <= 0 || rate.Window <= 0 {
+		panic("httputils: rate limit needs positive Requests and Window")
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByIP
+		if len(config.TrustedProxies) > 0 {
+			config.KeyFunc = KeyByForwardedIP(config.TrustedProxies...)
+		}
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	if config.Name == "" {
+		config.Name = rate.String()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := config.Store.Take(r.Context(), config.Name+"|"+config.KeyFunc(r), rate)
+			if err != nil {
+				// fail open, the store being down should not take the API down
+				log.Println("rate limit:", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(rate.burst()))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				retry := ceilSeconds(result.RetryAfter)
+				if retry < 1 {
+					retry = 1
+				}
+				header.Set("Retry-After", strconv.Itoa(retry))
+				WriteError(w, r, errs.ErrorTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the host part of r.RemoteAddr.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByIP limits each client IP.
+func KeyByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// KeyByForwardedIP limits each client IP behind trusted proxies, given as
+// IPs or CIDR ranges such as "10.0.0.0/8". Requests from other addresses are
+// limited by their own IP, so clients cannot pick their key by sending
+// X-Forwarded-For themselves. It panics on an invalid proxy.
+func KeyByForwardedIP(trustedProxies ...string) func(r *http.Request) string {
+	trusted := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic("httputils: invalid trusted proxy " + strconv.Quote(proxy))
+		}
+		trusted = append(trusted, network)
+	}
+	return func(r *http.Request) string {
+		return "ip:" + forwardedClientIP(r, trusted)
+	}
+}
+
+// forwardedClientIP walks X-Forwarded-For from the nearest hop while the
+// request came through trusted proxies and returns the first other address;
+// the entries further left may be forged by the client.
+func forwardedClientIP(r *http.Request, trusted []*net.IPNet) string {
+	ip := ClientIP(r)
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0 && trustedProxy(ip, trusted); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+	}
+	return ip
+}
+
+func trustedProxy(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyByHeader limits each value of header, such as an API key, and falls
+// back to the client IP when the header is missing.
+func KeyByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return name + ":" + v
+		}
+		return KeyByIP(r)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+type rateLimitEntry struct {
+	seen   time.Time
+	window time.Duration
+
+	// token bucket
+	tokens float64
+	last   time.Time
+
+	// sliding window
+	windowStart time.Time
+	previous    int
+	current     int
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitStore keeps limiter state in process memory.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		entries: make(map[string]*rateLimitEntry),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, rate Rate) (RateLimitResult, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &rateLimitEntry{}
+		s.entries[key] = e
+	}
+	e.seen = now
+	e.window = rate.Window
+
+	if rate.Algorithm == SlidingWindow {
+		return e.slidingWindow(now, rate), nil
+	}
+	return e.tokenBucket(now, rate), nil
+}
+
+// sweep drops keys idle for two of their windows, at most once per sweep
+// interval.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if now.Sub(e.seen) > 2*e.window {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func (e *rateLimitEntry) tokenBucket(now time.Time, rate Rate) RateLimitResult {
+	capacity := float64(rate.burst())
+	perSecond := float64(rate.Requests) / rate.Window.Seconds()
+
+	if e.last.IsZero() {
+		e.tokens = capacity
+	} else {
+		e.tokens = math.Min(capacity, e.tokens+now.Sub(e.last).Seconds()*perSecond)
+	}
+	e.last = now
+
+	var result RateLimitResult
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - e.tokens) / perSecond)
+	}
+	result.Remaining = int(e.tokens)
+	result.Reset = seconds((capacity - e.tokens) / perSecond)
+	return result
+}
+
+// slidingWindow weighs the previous window's count by how much of it still
+// overlaps the sliding window.
+func (e *rateLimitEntry) slidingWindow(now time.Time, rate Rate) RateLimitResult {
+	window := rate.Window
+	start := now.Truncate(window)
+	if !start.Equal(e.windowStart) {
+		if start.Sub(e.windowStart) == window {
+			e.previous = e.current
+		} else {
+			e.previous = 0
+		}
+		e.current = 0
+		e.windowStart = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+	count := float64(e.previous)*weight + float64(e.current)
+	limit := float64(rate.Requests)
+
+	var result RateLimitResult
+	if count+1 <= limit {
+		e.current++
+		count++
+		result.Allowed = true
+	} else if e.current >= rate.Requests || e.previous == 0 {
+		result.RetryAfter = window - elapsed
+	} else {
+		// wait until the previous window's weight leaves room for one more
+		needed := time.Duration(float64(window) * (1 - (limit-float64(e.current)-1)/float64(e.previous)))
+		result.RetryAfter = needed - elapsed
+	}
+	result.Remaining = int(math.Max(0, math.Floor(limit-count)))
+	result.Reset = window - elapsed
+	if e.previous > 0 {
+		result.Reset += window
+	}
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
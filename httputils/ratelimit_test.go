@@ -0,0 +1,159 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  Rate
+		steps []struct {
+			after time.Duration
+			want  bool
+		}
+	}{
+		{
+			name: "token bucket",
+			rate: Rate{Requests: 1, Window: time.Second, Burst: 2},
+			steps: []struct {
+				after time.Duration
+				want  bool
+			}{
+				{0, true},
+				{0, true},
+				{0, false},
+				{500 * time.Millisecond, false},
+				{500 * time.Millisecond, true},
+				{0, false},
+			},
+		},
+		{
+			name: "sliding window",
+			rate: Rate{Algorithm: SlidingWindow, Requests: 2, Window: time.Second},
+			steps: []struct {
+				after time.Duration
+				want  bool
+			}{
+				{0, true},
+				{0, true},
+				{0, false},
+				{time.Second, false},
+				{500 * time.Millisecond, true},
+				{0, false},
+				{time.Second, true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			store := NewMemoryRateLimitStore().(*memoryRateLimitStore)
+			store.now = func() time.Time { return now }
+
+			for i, step := range tt.steps {
+				now = now.Add(step.after)
+				result, err := store.Take(context.Background(), "key", tt.rate)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Allowed != step.want {
+					t.Errorf("step %d: Allowed = %v, want %v", i, result.Allowed, step.want)
+				}
+				if !result.Allowed && result.RetryAfter <= 0 {
+					t.Errorf("step %d: RetryAfter = %v, want > 0", i, result.RetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := RateLimit(RateLimitConfig{
+		Rate:    Rate{Requests: 1, Window: time.Minute},
+		KeyFunc: KeyByHeader("X-API-Key"),
+	})
+	handler := limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		apiKey     string
+		wantStatus int
+	}{
+		{name: "first request", apiKey: "a", wantStatus: http.StatusOK},
+		{name: "over limit", apiKey: "a", wantStatus: http.StatusTooManyRequests},
+		{name: "other key", apiKey: "b", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", tt.apiKey)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Header().Get("RateLimit-Limit") != "1" {
+				t.Errorf("RateLimit-Limit = %q, want %q", rec.Header().Get("RateLimit-Limit"), "1")
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+				t.Errorf("Retry-After is missing")
+			}
+		})
+	}
+}
+
+func TestKeyByForwardedIP(t *testing.T) {
+	key := KeyByForwardedIP("10.0.0.0/8", "192.168.1.1")
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:1234", want: "ip:203.0.113.7"},
+		{name: "forged header", remoteAddr: "203.0.113.7:1234", forwarded: "198.51.100.1", want: "ip:203.0.113.7"},
+		{name: "behind proxy", remoteAddr: "10.0.0.2:1234", forwarded: "198.51.100.1", want: "ip:198.51.100.1"},
+		{name: "through two proxies", remoteAddr: "10.0.0.2:1234", forwarded: "6.6.6.6, 198.51.100.1, 192.168.1.1", want: "ip:198.51.100.1"},
+		{name: "proxy without header", remoteAddr: "10.0.0.2:1234", want: "ip:10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := key(req); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitSharedStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	strict := RateLimit(RateLimitConfig{Rate: Rate{Requests: 1, Window: time.Minute}, Store: store})(ok)
+	loose := RateLimit(RateLimitConfig{Rate: Rate{Requests: 10, Window: time.Minute}, Store: store})(ok)
+
+	do := func(handler http.Handler) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+	do(strict)
+	if code := do(loose); code != http.StatusOK {
+		t.Errorf("loose limit after the strict one: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := do(strict); code != http.StatusTooManyRequests {
+		t.Errorf("strict limit: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,59 @@
+package httputils
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/huylqbk/codesample/logger"
+	"github.com/huylqbk/codesample/random"
+)
+
+const (
+	HeaderRequestID     = "X-Request-ID"
+	HeaderCorrelationID = "X-Correlation-ID"
+
+	maxRequestIDLength = 128
+)
+
+// RequestID reads X-Request-ID, or generates one, and stores it in the
+// request context for logger.WithContext. X-Correlation-ID is kept when
+// the caller sends it and defaults to the request ID. Both are echoed in
+// the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = random.NewUUID()
+		}
+		correlationID := r.Header.Get(HeaderCorrelationID)
+		if !validRequestID(correlationID) {
+			correlationID = requestID
+		}
+
+		w.Header().Set(HeaderRequestID, requestID)
+		w.Header().Set(HeaderCorrelationID, correlationID)
+
+		ctx := logger.WithRequestID(r.Context(), requestID)
+		ctx = logger.WithCorrelationID(ctx, correlationID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetRequestID returns the request ID set by the RequestID middleware.
+func GetRequestID(ctx context.Context) string {
+	return logger.RequestID(ctx)
+}
+
+// validRequestID rejects empty, oversized or non-printable IDs so client
+// input cannot break log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
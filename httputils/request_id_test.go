@@ -0,0 +1,81 @@
+package httputils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/huylqbk/codesample/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name              string
+		requestID         string
+		correlationID     string
+		wantRequestID     string
+		wantCorrelationID string
+	}{
+		{
+			name:              "generated",
+			wantRequestID:     "",
+			wantCorrelationID: "",
+		},
+		{
+			name:              "kept from request",
+			requestID:         "req-1",
+			wantRequestID:     "req-1",
+			wantCorrelationID: "req-1",
+		},
+		{
+			name:              "correlation kept from request",
+			requestID:         "req-1",
+			correlationID:     "corr-1",
+			wantRequestID:     "req-1",
+			wantCorrelationID: "corr-1",
+		},
+		{
+			name:              "invalid replaced",
+			requestID:         "bad id\n" + strings.Repeat("x", 10),
+			wantRequestID:     "",
+			wantCorrelationID: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRequestID, gotCorrelationID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRequestID = GetRequestID(r.Context())
+				gotCorrelationID = logger.CorrelationID(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(HeaderRequestID, tt.requestID)
+			}
+			if tt.correlationID != "" {
+				req.Header.Set(HeaderCorrelationID, tt.correlationID)
+			}
+			rec := httptest.NewRecorder()
+			RequestID(next).ServeHTTP(rec, req)
+
+			if gotRequestID == "" || gotRequestID == tt.requestID && tt.wantRequestID == "" {
+				t.Fatalf("request ID = %q, want a generated ID", gotRequestID)
+			}
+			if tt.wantRequestID != "" && gotRequestID != tt.wantRequestID {
+				t.Errorf("request ID = %q, want %q", gotRequestID, tt.wantRequestID)
+			}
+			wantCorrelationID := tt.wantCorrelationID
+			if wantCorrelationID == "" {
+				wantCorrelationID = gotRequestID
+			}
+			if gotCorrelationID != wantCorrelationID {
+				t.Errorf("correlation ID = %q, want %q", gotCorrelationID, wantCorrelationID)
+			}
+			if got := rec.Header().Get(HeaderRequestID); got != gotRequestID {
+				t.Errorf("response %s = %q, want %q", HeaderRequestID, got, gotRequestID)
+			}
+		})
+	}
+}
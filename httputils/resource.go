@@ -0,0 +1,329 @@
+package httputils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/huylqbk/codesample/database"
+	"github.com/huylqbk/codesample/errs"
+	"github.com/huylqbk/codesample/validation"
+)
+
+// Action is one of the operations a Resource serves.
+type Action string
+
+const (
+	ActionList   Action = "list"
+	ActionGet    Action = "get"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionPatch  Action = "patch"
+	ActionDelete Action = "delete"
+)
+
+var allActions = []Action{ActionList, ActionGet, ActionCreate, ActionUpdate, ActionPatch, ActionDelete}
+
+// ResourceConfig configures the routes of a model. Fields are named as in
+// JSON. Hooks that fail stop the request with their error, so return errs
+// sentinels such as errs.ErrorForbidden to choose the status.
+type ResourceConfig[T comparable] struct {
+	Path    string   // such as "/users"; items are served under Path + "/{id}"
+	Actions []Action // defaults to all
+	// Writable lists the fields clients may set; a body with any other field
+	// is refused. It defaults to every field but the primary key and the
+	// timestamps gorm maintains.
+	Writable   []string
+	Pagination PaginationConfig
+	Middleware []Middleware
+	// Scope narrows every query, such as to the caller's tenant; records
+	// outside it are not found.
+	Scope func(c Context, tx *gorm.DB) *gorm.DB
+	// Authorize runs before an action: with nil for list, the decoded record
+	// for create and the stored record otherwise.
+	Authorize func(c Context, action Action, record *T) error
+	// Validate runs on the record to store after its validate tags passed.
+	Validate func(c Context, action Action, record *T) error
+}
+
+// Resource serves list, get, create, update (PUT), patch and delete routes
+// for the model T over the database generics:
+//
+//	httputils.NewResource(db, httputils.ResourceConfig[User]{
+//		Path:     "/users",
+//		Writable: []string{"name", "email"},
+//	}).Mount(router)
+type Resource[T comparable] struct {
+	db       database.Database
+	config   ResourceConfig[T]
+	schema   *schema.Schema
+	pk       *schema.Field
+	writable map[string]*schema.Field // by JSON name
+}
+
+// NewResource panics when T is not a gorm model struct with a single primary key.
+func NewResource[T comparable](db database.Database, config ResourceConfig[T]) *Resource[T] {
+	var model T
+	if reflect.TypeOf(model).Kind() != reflect.Struct {
+		panic(fmt.Sprintf("httputils: resource %T is not a struct", model))
+	}
+	s, err := schema.Parse(&model, &gormSchemas, db.GetDB().NamingStrategy)
+	if err != nil {
+		panic(fmt.Sprintf("httputils: resource %T: %v", model, err))
+	}
+	if len(s.PrimaryFields) != 1 {
+		panic(fmt.Sprintf("httputils: resource %T needs a single primary key", model))
+	}
+	if len(config.Actions) == 0 {
+		config.Actions = allActions
+	}
+	config.Path = strings.TrimSuffix(config.Path, "/")
+
+	res := &Resource[T]{db: db, config: config, schema: s, pk: s.PrimaryFields[0], writable: map[string]*schema.Field{}}
+	allowed := make(map[string]bool, len(config.Writable))
+	for _, name := range config.Writable {
+		allowed[name] = true
+	}
+	for _, f := range s.Fields {
+		name := jsonName(f.StructField)
+		if name == "" || f.DBName == "" {
+			continue
+		}
+		if config.Writable != nil && allowed[name] ||
+			config.Writable == nil && !f.PrimaryKey && f.AutoCreateTime == 0 && f.AutoUpdateTime == 0 && f.FieldType != reflect.TypeOf(gorm.DeletedAt{}) {
+			res.writable[name] = f
+		}
+	}
+	return res
+}
+
+// Mount adds the routes to r.
+func (res *Resource[T]) Mount(r Router) Router {
+	res.mount(func(path, method string, handler HandlerFunc, op Operation) {
+		r.AddPath(path, method, handler, res.config.Middleware...)
+		r.Describe(path, method, op)
+	})
+	return r
+}
+
+// MountGroup adds the routes to g.
+func (res *Resource[T]) MountGroup(g Group) Group {
+	res.mount(func(path, method string, handler HandlerFunc, op Operation) {
+		g.AddPath(path, method, handler, res.config.Middleware...)
+		g.Describe(path, method, op)
+	})
+	return g
+}
+
+func (res *Resource[T]) mount(add func(path, method string, handler HandlerFunc, op Operation)) {
+	var model T
+	item := res.config.Path + "/{id}"
+	tags := []string{res.schema.Table}
+	for _, action := range res.config.Actions {
+		switch action {
+		case ActionList:
+			add(res.config.Path, "GET", res.list, Operation{Summary: "List " + res.schema.Table, Tags: tags, Responses: map[int]interface{}{http.StatusOK: Page[T]{}}})
+		case ActionGet:
+			add(item, "GET", res.get, Operation{Summary: "Get a " + res.schema.Name, Tags: tags, Responses: map[int]interface{}{http.StatusOK: model}})
+		case ActionCreate:
+			add(res.config.Path, "POST", res.create, Operation{Summary: "Create a " + res.schema.Name, Tags: tags, Request: model, Responses: map[int]interface{}{http.StatusCreated: model}})
+		case ActionUpdate:
+			add(item, "PUT", res.update, Operation{Summary: "Replace a " + res.schema.Name, Tags: tags, Request: model, Responses: map[int]interface{}{http.StatusOK: model}})
+		case ActionPatch:
+			add(item, "PATCH", res.patch, Operation{Summary: "Update a " + res.schema.Name, Tags: tags, Request: model, Responses: map[int]interface{}{http.StatusOK: model}})
+		case ActionDelete:
+			add(item, "DELETE", res.delete, Operation{Summary: "Delete a " + res.schema.Name, Tags: tags, Responses: map[int]interface{}{http.StatusNoContent: nil}})
+		default:
+			panic("httputils: unknown resource action " + string(action))
+		}
+	}
+}
+
+func (res *Resource[T]) list(c Context) error {
+	p, err := ParsePagination(c.Request(), res.config.Pagination)
+	if err != nil {
+		return err
+	}
+	if err := res.authorize(c, ActionList, nil); err != nil {
+		return err
+	}
+	page, err := Paginate[T](res.tx(c), p)
+	if err != nil {
+		return err
+	}
+	return page.Write(c.Response(), c.Request())
+}
+
+func (res *Resource[T]) get(c Context) error {
+	record, err := res.load(c, ActionGet)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, record)
+}
+
+func (res *Resource[T]) create(c Context) error {
+	var record T
+	if err := res.decode(c, &record); err != nil {
+		return err
+	}
+	if err := res.authorize(c, ActionCreate, &record); err != nil {
+		return err
+	}
+	if err := res.validate(c, ActionCreate, &record); err != nil {
+		return err
+	}
+	record, err := database.Create(res.tx(c), record)
+	if err != nil {
+		return err
+	}
+	id, _ := res.pk.ValueOf(c.Request().Context(), reflect.ValueOf(record))
+	c.Response().Header().Set("Location", fmt.Sprintf("%s/%v", c.Request().URL.Path, id))
+	return c.JSON(http.StatusCreated, record)
+}
+
+// update replaces the writable fields; those missing from the body are reset.
+func (res *Resource[T]) update(c Context) error {
+	return res.save(c, ActionUpdate, func(record *T) {
+		v := reflect.ValueOf(record).Elem()
+		for _, f := range res.writable {
+			fv := f.ReflectValueOf(c.Request().Context(), v)
+			fv.Set(reflect.Zero(fv.Type()))
+		}
+	})
+}
+
+// patch changes the fields in the body only.
+func (res *Resource[T]) patch(c Context) error {
+	return res.save(c, ActionPatch, func(*T) {})
+}
+
+func (res *Resource[T]) save(c Context, action Action, reset func(record *T)) error {
+	record, err := res.load(c, action)
+	if err != nil {
+		return err
+	}
+	id, _ := res.pk.ValueOf(c.Request().Context(), reflect.ValueOf(record))
+	reset(&record)
+	if err := res.decode(c, &record); err != nil {
+		return err
+	}
+	// the body cannot move the record
+	res.pk.Set(c.Request().Context(), reflect.ValueOf(&record).Elem(), id)
+	if err := res.validate(c, action, &record); err != nil {
+		return err
+	}
+	if record, err = database.Update(res.tx(c), record); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, record)
+}
+
+func (res *Resource[T]) delete(c Context) error {
+	record, err := res.load(c, ActionDelete)
+	if err != nil {
+		return err
+	}
+	if err := database.Delete(res.tx(c), record); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tx is the scoped session of a request.
+func (res *Resource[T]) tx(c Context) *gorm.DB {
+	tx := res.db.GetDB().WithContext(c.Request().Context())
+	if res.config.Scope != nil {
+		tx = res.config.Scope(c, tx)
+	}
+	return tx
+}
+
+// load finds the record of the {id} parameter and authorizes action on it.
+func (res *Resource[T]) load(c Context, action Action) (T, error) {
+	var record T
+	id := reflect.New(res.pk.FieldType).Elem()
+	if err := setValue(id, []string{c.Param("id")}); err != nil {
+		return record, errs.FieldErrors{{Field: "id", Message: err.Error()}}
+	}
+	// a condition on the column, as GetByID would read a string id as SQL
+	tx := res.tx(c).Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: res.pk.DBName},
+		Value:  id.Interface(),
+	})
+	records, err := database.GetAll[T](tx.Limit(1))
+	if err != nil {
+		return record, err
+	}
+	if len(records) == 0 {
+		return record, errors.Wrapf(errs.ErrorNotFound, "%s %s", res.schema.Name, c.Param("id"))
+	}
+	record = records[0]
+	return record, res.authorize(c, action, &record)
+}
+
+// decode reads the JSON body into record, refusing fields that are not writable.
+func (res *Resource[T]) decode(c Context, record *T) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return bodyError(err)
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(body, &present); err != nil {
+		return bodyError(err)
+	}
+	var fields errs.FieldErrors
+	for name := range present {
+		if res.writable[name] == nil {
+			fields = append(fields, errs.FieldError{Field: name, Message: "is not writable"})
+		}
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+	r := c.Request().Clone(c.Request().Context())
+	r.Header.Set("Content-Type", "application/json")
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return bindBody(r, record)
+}
+
+func (res *Resource[T]) authorize(c Context, action Action, record *T) error {
+	if res.config.Authorize == nil {
+		return nil
+	}
+	return res.config.Authorize(c, action, record)
+}
+
+func (res *Resource[T]) validate(c Context, action Action, record *T) error {
+	if err := validation.Struct(record); err != nil {
+		return err
+	}
+	if res.config.Validate == nil {
+		return nil
+	}
+	return res.config.Validate(c, action, record)
+}
+
+// jsonName is the JSON key of a field, or "" when it is not encoded.
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,158 @@
+package httputils
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+// dryRunDatabase builds SQL without running it, so reads find nothing.
+type dryRunDatabase struct {
+	db *gorm.DB
+}
+
+func (d dryRunDatabase) DB() (*sql.DB, error)                    { return d.db.DB() }
+func (d dryRunDatabase) GetDB() *gorm.DB                         { return d.db }
+func (d dryRunDatabase) Transaction() (*gorm.DB, func(), func()) { return d.db, func() {}, func() {} }
+func (d dryRunDatabase) Migrate() (int, error)                   { return 0, nil }
+
+type resourceUser struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name" validate:"required"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func TestResource(t *testing.T) {
+	db := dryRunDatabase{db: dryRunDB(t)}
+	users := NewResource(db, ResourceConfig[resourceUser]{
+		Path:     "/users",
+		Writable: []string{"name"},
+		Authorize: func(c Context, action Action, user *resourceUser) error {
+			if action == ActionCreate && user.Name == "root" {
+				return errs.ErrorForbidden
+			}
+			return nil
+		},
+		Validate: func(c Context, action Action, user *resourceUser) error {
+			if strings.TrimSpace(user.Name) != user.Name {
+				return errs.FieldErrors{{Field: "name", Message: "must be trimmed"}}
+			}
+			return nil
+		},
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{name: "list", method: "GET", path: "/users?sort=", wantStatus: http.StatusOK},
+		{name: "list with bad page", method: "GET", path: "/users?page=0", wantStatus: http.StatusBadRequest, wantField: "page"},
+		{name: "get missing", method: "GET", path: "/users/1", wantStatus: http.StatusNotFound},
+		{name: "get with bad id", method: "GET", path: "/users/abc", wantStatus: http.StatusBadRequest, wantField: "id"},
+		{name: "create", method: "POST", path: "/users", body: `{"name":"ann"}`, wantStatus: http.StatusCreated},
+		{name: "create read-only field", method: "POST", path: "/users", body: `{"name":"ann","role":"admin"}`, wantStatus: http.StatusBadRequest, wantField: "role"},
+		{name: "create invalid", method: "POST", path: "/users", body: `{}`, wantStatus: http.StatusBadRequest, wantField: "name"},
+		{name: "create rejected by hook", method: "POST", path: "/users", body: `{"name":" ann"}`, wantStatus: http.StatusBadRequest, wantField: "name"},
+		{name: "create unauthorized", method: "POST", path: "/users", body: `{"name":"root"}`, wantStatus: http.StatusForbidden},
+		{name: "patch missing", method: "PATCH", path: "/users/1", body: `{"name":"bob"}`, wantStatus: http.StatusNotFound},
+		{name: "delete missing", method: "DELETE", path: "/users/1", wantStatus: http.StatusNotFound},
+	}
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").AllowOpenAPI()
+			users.Mount(r)
+			handler, err := r.Handler()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, tt := range tests {
+				req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				if rec.Code != tt.wantStatus {
+					t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.wantStatus, rec.Body.String())
+				}
+				if tt.wantField != "" && !strings.Contains(rec.Body.String(), `"field":"`+tt.wantField+`"`) {
+					t.Errorf("%s: body = %s, want an error for %s", tt.name, rec.Body.String(), tt.wantField)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"/users/{id}"`) {
+				t.Errorf("openapi: status = %d, want the resource paths: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+type resourceDocument struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+}
+
+func TestResourceTextID(t *testing.T) {
+	r := NewChiRouter("0")
+	NewResource(dryRunDatabase{db: dryRunDB(t)}, ResourceConfig[resourceDocument]{Path: "/documents"}).Mount(r)
+	handler, err := r.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]int{
+		"/documents/" + uuid.New().String(): http.StatusNotFound,
+		"/documents/42":                     http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s: status = %d, want %d: %s", path, rec.Code, want, rec.Body.String())
+		}
+	}
+}
+
+func TestResourceWritable(t *testing.T) {
+	users := NewResource(dryRunDatabase{db: dryRunDB(t)}, ResourceConfig[resourceUser]{Path: "/users"})
+	for _, name := range []string{"name", "role"} {
+		if users.writable[name] == nil {
+			t.Errorf("%s is not writable by default", name)
+		}
+	}
+	for _, name := range []string{"id", "created_at"} {
+		if users.writable[name] != nil {
+			t.Errorf("%s is writable by default", name)
+		}
+	}
+}
+
+func TestResourceActions(t *testing.T) {
+	r := NewChiRouter("0")
+	NewResource(dryRunDatabase{db: dryRunDB(t)}, ResourceConfig[resourceUser]{
+		Path:    "/users",
+		Actions: []Action{ActionList, ActionGet},
+	}).Mount(r)
+	handler, err := r.Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"ann"}`)))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -1,11 +1,155 @@
 package httputils
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/huylqbk/codesample/health"
+)
+
+// HandlerFunc handles a request on any Router backend.
+type HandlerFunc func(c Context) error
+
+// Middleware wraps the request pipeline; it is applied the same way on every backend.
+type Middleware func(next http.Handler) http.Handler
+
 type Router interface {
 	Default()
 	ServeHTTP()
+	Run(ctx context.Context) error
+	// Handler assembles the routes, middleware and built-in endpoints without
+	// binding a port, for use with httptest. Admin endpoints with their own
+	// address are not included.
+	Handler() (http.Handler, error)
+	Addr() string
 	AddPrefix(prefix string) Router
+	AddPath(path, method string, handler HandlerFunc, middleware ...Middleware) Router
+	AddMethods(path string, methods []string, handler HandlerFunc, middleware ...Middleware) Router
+	AddMiddleware(middleware Middleware) Router
+	// AddWebSocket serves WebSocket connections on GET path.
+	AddWebSocket(path string, handler WebSocketHandler, config ...WebSocketConfig) Router
+	Group(prefix string, middleware ...Middleware) Group
 	AllowRecovery() Router
-	AllowLog() Router
+	// AllowLog writes a structured access log entry per request through
+	// logger.Logger.
+	AllowLog(config ...AccessLogConfig) Router
 	AllowHealthCheck() Router
-	AllowCors() Router
+	AllowCors(config ...CorsConfig) Router
+	AllowMetrics() Router
+	AllowRequestID() Router
+	AllowOpenAPI(config ...OpenAPIConfig) Router
+	AllowAdmin(config AdminConfig) Router
+	Describe(path, method string, op Operation) Router
+	AddLivenessCheck(name string, checker health.Checker, config ...health.Config) Router
+	AddReadinessCheck(name string, checker health.Checker, config ...health.Config) Router
+	SetShutdownTimeout(timeout time.Duration) Router
+	SetTLS(config TLSConfig) Router
+	SetServerConfig(config ServerConfig) Router
+	OnBeforeShutdown(hook ShutdownHook) Router
+	OnAfterShutdown(hook ShutdownHook) Router
+}
+
+// Route is a handler registered for one method and path.
+type Route struct {
+	Method     string
+	Path       string
+	Handler    HandlerFunc
+	Middleware []Middleware
+	group      *routeGroup
+}
+
+// paramsFunc resolves a path parameter the way a backend stores it.
+type paramsFunc func(r *http.Request, name string) string
+
+// handler wraps the route handler in its middleware, first one outermost.
+func (rt Route) handler(params paramsFunc) http.Handler {
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve(rt.Handler, w, r, func(name string) string {
+			return params(r, name)
+		})
+	})
+	for i := len(rt.Middleware) - 1; i >= 0; i-- {
+		h = rt.Middleware[i](h)
+	}
+	return h
+}
+
+// use returns a copy of rs with middleware wrapped around every route.
+func (rs routes) use(middleware func(rt Route) Middleware) routes {
+	out := make(routes, len(rs))
+	for i, rt := range rs {
+		rt.Middleware = append([]Middleware{middleware(rt)}, rt.Middleware...)
+		out[i] = rt
+	}
+	return out
+}
+
+// routes keeps handlers in registration order.
+type routes []Route
+
+// grouped returns a copy of rs with each route's group middleware ahead of its own.
+func (rs routes) grouped() routes {
+	out := make(routes, len(rs))
+	for i, rt := range rs {
+		if chain := rt.group.chain(); len(chain) > 0 {
+			rt.Middleware = append(chain, rt.Middleware...)
+		}
+		rt.group = nil
+		out[i] = rt
+	}
+	return out
+}
+
+func (rs *routes) add(path string, methods []string, handler HandlerFunc, middleware ...Middleware) {
+	for _, method := range methods {
+		*rs = append(*rs, Route{
+			Method:     strings.ToUpper(method),
+			Path:       path,
+			Handler:    handler,
+			Middleware: middleware,
+		})
+	}
+}
+
+// routeMethods are the request methods every backend can route; chi panics on
+// any other.
+var routeMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true,
+	http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+	http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// validate reports the first route with a method no backend can serve, or
+// the first method and path registered more than once.
+func (rs routes) validate() error {
+	seen := make(map[string]bool, len(rs))
+	for _, route := range rs {
+		if !routeMethods[route.Method] {
+			return fmt.Errorf("unsupported method %q for %s", route.Method, route.Path)
+		}
+		key := route.Method + " " + route.Path
+		if seen[key] {
+			return fmt.Errorf("duplicate route: %s", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// echoPath converts "{id}" style parameters to echo's ":id" style. echo
+// cannot match on a pattern, so a constrained "{id:[0-9]+}" is an error
+// rather than a route that accepts anything.
+func echoPath(path string) (string, error) {
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		if m[2] != "" {
+			return "", fmt.Errorf("echo: path %s: parameter %q has a pattern, which echo does not support", path, m[1])
+		}
+	}
+	return pathParam.ReplaceAllString(path, ":$1"), nil
 }
@@ -0,0 +1,99 @@
+package httputils
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRoutesValidate(t *testing.T) {
+	handler := func(c Context) error { return nil }
+
+	tests := []struct {
+		name    string
+		add     func(rs *routes)
+		wantErr bool
+	}{
+		{
+			name: "different methods on same path",
+			add: func(rs *routes) {
+				rs.add("/users", []string{"GET"}, handler)
+				rs.add("/users", []string{"POST"}, handler)
+			},
+			wantErr: false,
+		},
+		{
+			name: "method set",
+			add: func(rs *routes) {
+				rs.add("/users", []string{"PUT", "PATCH"}, handler)
+			},
+			wantErr: false,
+		},
+		{
+			name: "non-standard method",
+			add: func(rs *routes) {
+				rs.add("/cache", []string{"PURGE"}, handler)
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate method and path",
+			add: func(rs *routes) {
+				rs.add("/users", []string{"GET"}, handler)
+				rs.add("/users", []string{"get"}, handler)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rs routes
+			tt.add(&rs)
+			if err := rs.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnsupportedMethod(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").AddPath("/cache", "PURGE", WrapHandler(http.NotFoundHandler()))
+			if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), "PURGE") {
+				t.Errorf("Handler() error = %v, want unsupported method", err)
+			}
+		})
+	}
+}
+
+func TestEchoPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "/users", want: "/users"},
+		{path: "/users/{id}", want: "/users/:id"},
+		{path: "/users/{id}/orders/{orderID}", want: "/users/:id/orders/:orderID"},
+		{path: "/users/{id:[0-9]+}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := echoPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("echoPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("echoPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEchoPatternRoute(t *testing.T) {
+	r := NewEchoRouter("0").AddPath("/users/{id:[0-9]+}", "GET", WrapHandler(http.NotFoundHandler()))
+	if _, err := r.Handler(); err == nil || !strings.Contains(err.Error(), "/users/{id:[0-9]+}") {
+		t.Errorf("Handler() error = %v, want pattern error", err)
+	}
+}
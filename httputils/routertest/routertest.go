@@ -0,0 +1,170 @@
+// Package routertest issues in-process requests against an httputils.Router
+// and asserts on the responses.
+//
+//	c := routertest.New(t, router)
+//	c.Post("/users", user).AssertStatus(http.StatusCreated)
+//	c.Get("/users/1").AssertStatus(http.StatusOK).AssertJSON(user)
+package routertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/huylqbk/codesample/httputils"
+)
+
+// Client sends requests straight to a router's handler; Header is added to
+// every request.
+type Client struct {
+	t       testing.TB
+	handler http.Handler
+	Header  http.Header
+}
+
+// New builds r and fails t if it does not build.
+func New(t testing.TB, r httputils.Router) *Client {
+	t.Helper()
+	handler, err := r.Handler()
+	if err != nil {
+		t.Fatalf("routertest: build router: %v", err)
+	}
+	return &Client{t: t, handler: handler, Header: http.Header{}}
+}
+
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	return c.Request(http.MethodGet, path, nil)
+}
+
+func (c *Client) Post(path string, body interface{}) *Response {
+	c.t.Helper()
+	return c.Request(http.MethodPost, path, body)
+}
+
+func (c *Client) Put(path string, body interface{}) *Response {
+	c.t.Helper()
+	return c.Request(http.MethodPut, path, body)
+}
+
+func (c *Client) Patch(path string, body interface{}) *Response {
+	c.t.Helper()
+	return c.Request(http.MethodPatch, path, body)
+}
+
+func (c *Client) Delete(path string) *Response {
+	c.t.Helper()
+	return c.Request(http.MethodDelete, path, nil)
+}
+
+// Request sends body as JSON; a string, []byte or io.Reader is sent as is.
+func (c *Client) Request(method, path string, body interface{}) *Response {
+	c.t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("routertest: encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	for name, values := range c.Header {
+		req.Header[name] = values
+	}
+	if reader != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.Do(req)
+}
+
+// Do sends req as is.
+func (c *Client) Do(req *http.Request) *Response {
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return &Response{ResponseRecorder: rec, t: c.t}
+}
+
+// Response is a recorded response with chainable assertions.
+type Response struct {
+	*httptest.ResponseRecorder
+	t testing.TB
+}
+
+func (r *Response) AssertStatus(code int) *Response {
+	r.t.Helper()
+	if r.Code != code {
+		r.t.Errorf("status = %d, want %d: %s", r.Code, code, r.Body.String())
+	}
+	return r
+}
+
+func (r *Response) AssertHeader(name, value string) *Response {
+	r.t.Helper()
+	if got := r.Header().Get(name); got != value {
+		r.t.Errorf("header %s = %q, want %q", name, got, value)
+	}
+	return r
+}
+
+// AssertJSON compares the body with want as JSON, ignoring key order and
+// formatting. want may be a value to encode or a JSON string.
+func (r *Response) AssertJSON(want interface{}) *Response {
+	r.t.Helper()
+	var wantData []byte
+	switch w := want.(type) {
+	case string:
+		wantData = []byte(w)
+	case []byte:
+		wantData = w
+	default:
+		var err error
+		if wantData, err = json.Marshal(want); err != nil {
+			r.t.Fatalf("routertest: encode want: %v", err)
+		}
+	}
+
+	var got, expected interface{}
+	if err := json.Unmarshal(r.Body.Bytes(), &got); err != nil {
+		r.t.Errorf("body is not JSON: %v: %s", err, r.Body.String())
+		return r
+	}
+	if err := json.Unmarshal(wantData, &expected); err != nil {
+		r.t.Fatalf("routertest: decode want: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		r.t.Errorf("body = %s, want %s", r.Body.String(), wantData)
+	}
+	return r
+}
+
+// DecodeJSON decodes the body into v.
+func (r *Response) DecodeJSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		r.t.Errorf("decode body: %v: %s", err, r.Body.String())
+	}
+	return r
+}
+
+// Problem decodes an application/problem+json body.
+func (r *Response) Problem() httputils.Problem {
+	r.t.Helper()
+	var p httputils.Problem
+	r.AssertHeader("Content-Type", httputils.ContentTypeProblem).DecodeJSON(&p)
+	return p
+}
@@ -0,0 +1,54 @@
+package routertest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/huylqbk/codesample/errs"
+	"github.com/huylqbk/codesample/httputils"
+)
+
+type user struct {
+	ID   string `json:"id" param:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+func TestClient(t *testing.T) {
+	backends := map[string]func(port string) httputils.Router{
+		"chi":  httputils.NewChiRouter,
+		"echo": httputils.NewEchoRouter,
+		"mux":  httputils.NewMuxRouter,
+	}
+	for name, newRouter := range backends {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").
+				AddPrefix("/api").
+				AllowHealthCheck().
+				AllowRequestID().
+				AddPath("/users/{id}", "PUT", func(c httputils.Context) error {
+					var u user
+					if err := c.Bind(&u); err != nil {
+						return err
+					}
+					return c.JSON(http.StatusOK, u)
+				}).
+				AddPath("/users/{id}", "GET", func(c httputils.Context) error {
+					return errs.ErrorResourceNotFound
+				})
+
+			c := New(t, r)
+			c.Header.Set(httputils.HeaderRequestID, "test-1")
+			c.Put("/api/users/7", user{Name: "ann"}).
+				AssertStatus(http.StatusOK).
+				AssertHeader(httputils.HeaderRequestID, "test-1").
+				AssertJSON(`{"name": "ann", "id": "7"}`)
+
+			resp := c.Put("/api/users/7", `{}`).AssertStatus(http.StatusBadRequest)
+			if p := resp.Problem(); len(p.Errors) != 1 || p.Errors[0].Field != "name" || p.RequestID != "test-1" {
+				t.Errorf("problem = %+v, want name field error", p)
+			}
+			c.Get("/api/users/7").AssertStatus(http.StatusBadRequest)
+			c.Get("/api/readyz").AssertStatus(http.StatusOK)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/huylqbk/codesample/config"
+)
+
+const (
+	defaultShutdownTimeout   = 10 * time.Second
+	defaultReadTimeout       = 60 * time.Second
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultWriteTimeout      = 60 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+)
+
+// ServerConfig limits every connection of a Router. Zero values take the
+// defaults and negative values disable a limit. The write timeout bounds the
+// whole response, so set it to fit the slowest route, such as exports, and
+// give the shorter routes a Timeout middleware.
+type ServerConfig struct {
+	ReadTimeout       time.Duration // defaults to 60s
+	ReadHeaderTimeout time.Duration // defaults to 10s
+	WriteTimeout      time.Duration // defaults to 60s
+	IdleTimeout       time.Duration // keep-alive connections, defaults to 120s
+	MaxHeaderBytes    int           // defaults to http.DefaultMaxHeaderBytes
+	MaxBodyBytes      int64         // larger bodies get 413, defaults to no limit
+}
+
+// ServerConfigFrom reads the server settings of the service config.
+func ServerConfigFrom(c config.Server) ServerConfig {
+	return ServerConfig{
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		MaxBodyBytes:      c.MaxBodyBytes,
+	}
+}
+
+// orDefault returns d when it is zero, and 0, meaning none, when it is negative.
+func orDefault(d, def time.Duration) time.Duration {
+	switch {
+	case d == 0:
+		return def
+	case d < 0:
+		return 0
+	}
+	return d
+}
+
+// ShutdownHook runs while the server drains; ctx expires with the drain timeout.
+type ShutdownHook func(ctx context.Context) error
+
+// lifecycle holds the graceful shutdown settings shared by every Router.
+type lifecycle struct {
+	shutdownTimeout time.Duration
+	beforeShutdown  []ShutdownHook
+	afterShutdown   []ShutdownHook
+	tls             *TLSConfig
+	server          ServerConfig
+	draining        int32
+	addr            atomic.Value
+	cancel          atomic.Value // context.CancelFunc of the running server
+}
+
+func (l *lifecycle) isDraining() bool {
+	return atomic.LoadInt32(&l.draining) == 1
+}
+
+// serving fails once the server starts draining.
+func (l *lifecycle) serving(ctx context.Context) error {
+	if l.isDraining() {
+		return errors.New("service is shutting down")
+	}
+	return nil
+}
+
+// Addr returns the address the server is bound to, or "" before it listens.
+func (l *lifecycle) Addr() string {
+	addr, _ := l.addr.Load().(string)
+	return addr
+}
+
+// stop starts a graceful shutdown of the running server; it reports false
+// when the server is not running.
+func (l *lifecycle) stop() bool {
+	cancel, _ := l.cancel.Load().(context.CancelFunc)
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	return true
+}
+
+// httpServer returns a server for handler with the configured limits.
+func (l *lifecycle) httpServer(addr string, handler http.Handler) *http.Server {
+	c := l.server
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       orDefault(c.ReadTimeout, defaultReadTimeout),
+		ReadHeaderTimeout: orDefault(c.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		WriteTimeout:      orDefault(c.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       orDefault(c.IdleTimeout, defaultIdleTimeout),
+	}
+	if c.MaxHeaderBytes > 0 {
+		server.MaxHeaderBytes = c.MaxHeaderBytes
+	}
+	return server
+}
+
+// listen serves until ctx is done, then drains in-flight requests; it serves
+// HTTPS when TLS is set. It returns the listen error, or the first error met
+// while shutting down.
+func (l *lifecycle) listen(ctx context.Context, server *http.Server) error {
+	if l.tls != nil {
+		certs, err := newCertReloader(*l.tls)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = certs.tlsConfig()
+	}
+
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	l.cancel.Store(cancel)
+	defer l.cancel.Store(context.CancelFunc(nil))
+	atomic.StoreInt32(&l.draining, 0)
+	l.addr.Store(ln.Addr().String())
+	defer l.addr.Store("")
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Println("Server started on: " + ln.Addr().String())
+		if server.TLSConfig != nil {
+			errs <- server.ServeTLS(ln, "", "")
+		} else {
+			errs <- server.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	return l.shutdown(server)
+}
+
+func (l *lifecycle) shutdown(server *http.Server) error {
+	atomic.StoreInt32(&l.draining, 1)
+
+	timeout := l.shutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	for _, hook := range l.beforeShutdown {
+		keep(hook(ctx))
+	}
+	keep(server.Shutdown(ctx))
+	for _, hook := range l.afterShutdown {
+		keep(hook(ctx))
+	}
+	log.Println("router stopped")
+	return first
+}
+
+// serveUntilSignal runs the router until SIGINT or SIGTERM and exits on failure.
+func serveUntilSignal(r Router) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	if err := r.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,158 @@
+package httputils
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func backends() map[string]func(port string) Router {
+	return map[string]func(port string) Router{
+		"chi":  NewChiRouter,
+		"echo": NewEchoRouter,
+		"mux":  NewMuxRouter,
+	}
+}
+
+// start runs r in the background and waits until it is bound.
+func start(t *testing.T, r Router) (addr string, stop func() error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+	for i := 0; i < 100 && r.Addr() == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r.Addr() == "" {
+		cancel()
+		t.Fatalf("router did not start: %v", <-done)
+	}
+	return r.Addr(), func() error {
+		cancel()
+		return <-done
+	}
+}
+
+func TestRun(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			shutdown := false
+			r := newRouter("0").
+				AddPrefix("/api").
+				AddPath("/users/{id}", "GET", func(c Context) error {
+					time.Sleep(100 * time.Millisecond)
+					return c.String(http.StatusOK, c.Param("id"))
+				}).
+				OnAfterShutdown(func(ctx context.Context) error {
+					shutdown = true
+					return nil
+				})
+			addr, stop := start(t, r)
+
+			got := make(chan string, 1)
+			go func() {
+				resp, err := http.Get("http://" + addr + "/api/users/42")
+				if err != nil {
+					got <- err.Error()
+					return
+				}
+				defer resp.Body.Close()
+				body, _ := io.ReadAll(resp.Body)
+				got <- string(body)
+			}()
+			time.Sleep(50 * time.Millisecond)
+
+			if err := stop(); err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+			if body := <-got; body != "42" {
+				t.Errorf("in-flight request = %q, want %q", body, "42")
+			}
+			if !shutdown {
+				t.Errorf("after shutdown hook was not called")
+			}
+		})
+	}
+}
+
+func TestRunDuplicateRoute(t *testing.T) {
+	handler := func(c Context) error { return nil }
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").
+				AddPath("/health", "GET", handler).
+				AllowHealthCheck()
+			if err := r.Run(context.Background()); err == nil {
+				t.Errorf("Run() error = nil, want duplicate route error")
+			}
+		})
+	}
+}
+
+func TestReadinessDuringShutdown(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			status := make(chan int, 1)
+			var addr string
+			r := newRouter("0").
+				AllowHealthCheck().
+				OnBeforeShutdown(func(ctx context.Context) error {
+					resp, err := http.Get("http://" + addr + "/readyz")
+					if err != nil {
+						return err
+					}
+					resp.Body.Close()
+					status <- resp.StatusCode
+					return nil
+				})
+			addr, stop := start(t, r)
+
+			if err := stop(); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got := <-status; got != http.StatusServiceUnavailable {
+				t.Errorf("/readyz while draining = %d, want %d", got, http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+func TestShutdownHookOrder(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			var addr string
+			var events []string
+			listening := func() string {
+				conn, err := net.DialTimeout("tcp", addr, time.Second)
+				if err != nil {
+					return "closed"
+				}
+				conn.Close()
+				return "open"
+			}
+			r := newRouter("0").
+				OnBeforeShutdown(func(ctx context.Context) error {
+					events = append(events, "before:"+listening())
+					return nil
+				}).
+				OnAfterShutdown(func(ctx context.Context) error {
+					events = append(events, "after:"+listening())
+					return nil
+				})
+			addr, stop := start(t, r)
+
+			if err := stop(); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got, want := strings.Join(events, ","), "before:open,after:closed"; got != want {
+				t.Errorf("hooks = %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ContentTypeEventStream = "text/event-stream"
+	HeaderLastEventID      = "Last-Event-ID"
+
+	defaultSSEHeartbeat = 15 * time.Second
+)
+
+// ErrStreamingUnsupported is returned when the response cannot be flushed.
+var ErrStreamingUnsupported = errors.New("httputils: response writer does not support flushing")
+
+// Event is one server-sent event. Data is written as is when it is a string
+// or []byte and as JSON otherwise; multi-line data is split into data lines.
+type Event struct {
+	ID    string
+	Event string
+	Data  interface{}
+	Retry time.Duration
+}
+
+type SSEConfig struct {
+	// Heartbeat writes a comment at this interval so proxies keep the
+	// connection open; defaults to 15 seconds, a negative value disables it.
+	Heartbeat time.Duration
+	// Retry is sent once at the start as the client reconnection delay.
+	Retry time.Duration
+}
+
+// SSE streams server-sent events on a response. It is safe for concurrent use.
+type SSE struct {
+	w           http.ResponseWriter
+	flusher     http.Flusher
+	ctx         context.Context
+	cancel      context.CancelFunc
+	lastEventID string
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewSSE starts an event stream on w. The stream's Context is done when the
+// client disconnects or Close is called; Send then fails. From Go 1.20 the
+// stream is not cut by the server's WriteTimeout, provided every middleware
+// writer around w has an Unwrap method, as with http.ResponseController. On
+// echo, requests must accept text/event-stream, as EventSource's do, to
+// bypass gzip. Close the stream before the handler returns:
+//
+//	stream, err := httputils.NewSSE(c.Response(), c.Request())
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+func NewSSE(w http.ResponseWriter, r *http.Request, config ...SSEConfig) (*SSE, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrStreamingUnsupported
+	}
+	var c SSEConfig
+	if len(config) > 0 {
+		c = config[0]
+	}
+	if c.Heartbeat == 0 {
+		c.Heartbeat = defaultSSEHeartbeat
+	}
+
+	clearWriteDeadline(w)
+
+	header := w.Header()
+	header.Set("Content-Type", ContentTypeEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	s := &SSE{
+		w:           w,
+		flusher:     flusher,
+		ctx:         ctx,
+		cancel:      cancel,
+		lastEventID: r.Header.Get(HeaderLastEventID),
+	}
+	if c.Retry > 0 {
+		s.write(fmt.Sprintf("retry: %d\n\n", c.Retry.Milliseconds()))
+	} else {
+		s.write(": connected\n\n")
+	}
+	if c.Heartbeat > 0 {
+		go s.heartbeat(c.Heartbeat)
+	}
+	return s, s.err
+}
+
+// clearWriteDeadline lifts the server's WriteTimeout for the connection
+// behind w, unwrapping middleware writers down to the one net/http created.
+// That writer can set deadlines from Go 1.20; on older releases the stream
+// still ends at WriteTimeout.
+func clearWriteDeadline(w http.ResponseWriter) {
+	for {
+		switch rw := w.(type) {
+		case interface{ SetWriteDeadline(time.Time) error }:
+			rw.SetWriteDeadline(time.Time{})
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		case *echo.Response:
+			w = rw.Writer
+		default:
+			return
+		}
+	}
+}
+
+// LastEventID is the ID of the last event the client saw before it
+// reconnected, from the Last-Event-ID header, or "".
+func (s *SSE) LastEventID() string {
+	return s.lastEventID
+}
+
+// Context is done once the client disconnects or the stream is closed.
+func (s *SSE) Context() context.Context {
+	return s.ctx
+}
+
+// Send writes and flushes e.
+func (s *SSE) Send(e Event) error {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + oneLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + oneLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Close stops the heartbeat and marks the stream done. No write happens
+// after it returns.
+func (s *SSE) Close() {
+	s.cancel()
+	s.mu.Lock() // wait for a write in progress
+	s.mu.Unlock()
+}
+
+func (s *SSE) write(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.w.Write([]byte(msg)); err != nil {
+		s.err = err
+		s.cancel()
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *SSE) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if s.write(": ping\n\n") != nil {
+				return
+			}
+		}
+	}
+}
+
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// acceptsEventStream reports whether r asks for an event stream, as
+// EventSource does.
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ContentTypeEventStream)
+}
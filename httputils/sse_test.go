@@ -0,0 +1,114 @@
+package httputils
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			disconnected := make(chan struct{})
+			r := newRouter("0").AllowMetrics().AddPath("/events", "GET", func(c Context) error {
+				stream, err := NewSSE(c.Response(), c.Request(), SSEConfig{Heartbeat: 10 * time.Millisecond, Retry: time.Second})
+				if err != nil {
+					return err
+				}
+				defer stream.Close()
+
+				next, _ := strconv.Atoi(stream.LastEventID())
+				for i := next + 1; i <= next+2; i++ {
+					if err := stream.Send(Event{ID: strconv.Itoa(i), Event: "progress", Data: map[string]int{"step": i}}); err != nil {
+						return err
+					}
+				}
+				<-stream.Context().Done()
+				close(disconnected)
+				return nil
+			})
+			addr, stop := start(t, r)
+			defer stop()
+
+			req, _ := http.NewRequest("GET", "http://"+addr+"/events", nil)
+			req.Header.Set("Accept", ContentTypeEventStream)
+			req.Header.Set("Accept-Encoding", "gzip")
+			req.Header.Set(HeaderLastEventID, "4")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != ContentTypeEventStream {
+				t.Errorf("content type = %q", ct)
+			}
+			if ce := resp.Header.Get("Content-Encoding"); ce != "" {
+				t.Errorf("content encoding = %q, want none", ce)
+			}
+
+			var lines []string
+			reader := bufio.NewReader(resp.Body)
+			for len(lines) < 12 {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					t.Fatalf("read: %v after %q", err, lines)
+				}
+				lines = append(lines, strings.TrimSuffix(line, "\n"))
+			}
+			stream := strings.Join(lines, "\n")
+			for _, want := range []string{
+				"retry: 1000\n",
+				"id: 5\nevent: progress\ndata: {\"step\":5}\n",
+				"id: 6\nevent: progress\ndata: {\"step\":6}\n",
+				": ping\n",
+			} {
+				if !strings.Contains(stream, want) {
+					t.Errorf("stream does not contain %q:\n%s", want, stream)
+				}
+			}
+
+			resp.Body.Close()
+			select {
+			case <-disconnected:
+			case <-time.After(2 * time.Second):
+				t.Errorf("handler did not see the client disconnect")
+			}
+		})
+	}
+}
+
+func TestSSEOutlivesWriteTimeout(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").
+				SetServerConfig(ServerConfig{WriteTimeout: 200 * time.Millisecond}).
+				AddPath("/events", "GET", func(c Context) error {
+					stream, err := NewSSE(c.Response(), c.Request(), SSEConfig{Heartbeat: 50 * time.Millisecond})
+					if err != nil {
+						return err
+					}
+					defer stream.Close()
+					<-stream.Context().Done()
+					return nil
+				})
+			addr, stop := start(t, r)
+			defer stop()
+
+			req, _ := http.NewRequest("GET", "http://"+addr+"/events", nil)
+			req.Header.Set("Accept", ContentTypeEventStream)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			reader := bufio.NewReader(resp.Body)
+			for began := time.Now(); time.Since(began) < 600*time.Millisecond; {
+				if _, err := reader.ReadString('\n'); err != nil {
+					t.Fatalf("stream ended after %v: %v", time.Since(began), err)
+				}
+			}
+		})
+	}
+}
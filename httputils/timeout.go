@@ -0,0 +1,141 @@
+package httputils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+// Timeout cancels the request context after timeout and answers 503 with an
+// errs.ErrorTimeout problem, whatever the handler writes once the deadline
+// passed. The response is buffered until the handler returns, so it does not
+// suit streaming routes. Pass it to AddPath or a Group to give routes their
+// own limits; it cannot extend the server's WriteTimeout.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header), status: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+						return
+					}
+					close(done)
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p) // for Recovery on this goroutine
+			case <-done:
+			case <-ctx.Done():
+			}
+
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				WriteError(w, r, errs.ErrorTimeout)
+				return
+			}
+			if r.Context().Err() != nil {
+				return // the client is gone
+			}
+			header := w.Header()
+			for name, values := range tw.header {
+				header[name] = values
+			}
+			w.WriteHeader(tw.status)
+			w.Write(tw.body.Bytes())
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the Timeout deadline.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	return w.body.Write(b)
+}
+
+// MaxBodySize answers 413 with an errs.ErrorRequestTooLarge problem for
+// bodies over limit bytes. A body without a Content-Length fails with that
+// error once it is read past the limit, which Context.Bind reports as 413.
+func MaxBodySize(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				WriteError(w, r, errs.ErrorRequestTooLarge)
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = &limitedBody{ReadCloser: r.Body, remaining: limit}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// at the limit, any more data is too much
+		var probe [1]byte
+		n, err := b.ReadCloser.Read(probe[:])
+		if n > 0 {
+			return 0, errs.ErrorRequestTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
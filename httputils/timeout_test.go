@@ -0,0 +1,138 @@
+package httputils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "in time",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Done", "1")
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, "done")
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   "done",
+		},
+		{
+			name: "honours the context",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				WriteError(w, r, r.Context().Err())
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "ignores the context",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				io.WriteString(w, "late")
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Timeout(50*time.Millisecond)(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				if ct := rec.Header().Get("Content-Type"); ct != ContentTypeProblem {
+					t.Errorf("Content-Type = %q, want %q", ct, ContentTypeProblem)
+				}
+				if !strings.Contains(rec.Body.String(), ProblemTypeBase+"timeout") {
+					t.Errorf("body = %s, want the timeout problem", rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	handler := Recovery(Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	var l lifecycle
+	server := l.httpServer(":0", nil)
+	if server.ReadTimeout != defaultReadTimeout || server.ReadHeaderTimeout != defaultReadHeaderTimeout ||
+		server.WriteTimeout != defaultWriteTimeout || server.IdleTimeout != defaultIdleTimeout {
+		t.Errorf("default timeouts = %v, %v, %v, %v", server.ReadTimeout, server.ReadHeaderTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+
+	l.server = ServerConfig{WriteTimeout: -1, ReadTimeout: time.Second, MaxHeaderBytes: 4096}
+	server = l.httpServer(":0", nil)
+	if server.WriteTimeout != 0 || server.ReadTimeout != time.Second || server.MaxHeaderBytes != 4096 {
+		t.Errorf("server = %+v, want no write timeout, 1s read timeout and 4096 header bytes", server)
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	type input struct {
+		Name string `json:"name"`
+	}
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").SetServerConfig(ServerConfig{MaxBodyBytes: 16})
+			r.AddPath("/echo", "POST", func(c Context) error {
+				var in input
+				if err := c.Bind(&in); err != nil {
+					return err
+				}
+				return c.JSON(http.StatusOK, in)
+			})
+			handler, err := r.Handler()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				name       string
+				body       string
+				chunked    bool
+				wantStatus int
+			}{
+				{name: "small", body: `{"name":"a"}`, wantStatus: http.StatusOK},
+				{name: "large", body: `{"name":"abcdefghijkl"}`, wantStatus: http.StatusRequestEntityTooLarge},
+				{name: "large chunked", body: `{"name":"abcdefghijkl"}`, chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+			}
+			for _, tt := range tests {
+				req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				if tt.chunked {
+					req.ContentLength = -1
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != tt.wantStatus {
+					t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.wantStatus, rec.Body.String())
+				}
+			}
+		})
+	}
+}
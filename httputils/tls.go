@@ -0,0 +1,153 @@
+package httputils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultTLSReloadInterval = 10 * time.Second
+
+// TLSConfig serves HTTPS from PEM files. Setting ClientCAFile turns on
+// mutual TLS. The files are checked for changes at most once per
+// ReloadInterval and reloaded without a restart; a broken file is logged
+// and the previous certificate is kept.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string             // CA bundle that client certificates are verified against
+	ClientAuth   tls.ClientAuthType // defaults to tls.RequireAndVerifyClientCert with ClientCAFile
+	MinVersion   uint16             // defaults to tls.VersionTLS12
+	CipherSuites []uint16           // defaults to Go's; ignored for TLS 1.3
+	// ReloadInterval defaults to 10 seconds; a negative value disables reloading.
+	ReloadInterval time.Duration
+}
+
+// certReloader holds the current certificate and client CA pool and reloads
+// them when their files change.
+type certReloader struct {
+	config TLSConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(config TLSConfig) (*certReloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, fmt.Errorf("tls: CertFile and KeyFile are required")
+	}
+	if config.ReloadInterval == 0 {
+		config.ReloadInterval = defaultTLSReloadInterval
+	}
+	c := &certReloader{config: config, now: time.Now}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// tlsConfig returns the server configuration. Certificates and client CAs
+// are resolved per handshake so reloads apply to new connections.
+func (c *certReloader) tlsConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion:   c.config.MinVersion,
+		CipherSuites: c.config.CipherSuites,
+	}
+	if base.MinVersion == 0 {
+		base.MinVersion = tls.VersionTLS12
+	}
+	base.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, _ := c.current()
+		return cert, nil
+	}
+	if c.config.ClientCAFile == "" {
+		return base
+	}
+
+	clientAuth := c.config.ClientAuth
+	if clientAuth == tls.NoClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+	base.ClientAuth = clientAuth
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		_, pool := c.current()
+		config := base.Clone()
+		config.ClientCAs = pool
+		config.GetConfigForClient = nil
+		return config, nil
+	}
+	return base
+}
+
+func (c *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if c.config.ReloadInterval > 0 && now.Sub(c.checkedAt) >= c.config.ReloadInterval {
+		c.checkedAt = now
+		if c.changed() {
+			if err := c.load(); err != nil {
+				log.Println("tls: keeping previous certificate:", err)
+			} else {
+				log.Println("tls: certificate reloaded")
+			}
+		}
+	}
+	return c.cert, c.clientCAs
+}
+
+func (c *certReloader) files() []string {
+	files := []string{c.config.CertFile, c.config.KeyFile}
+	if c.config.ClientCAFile != "" {
+		files = append(files, c.config.ClientCAFile)
+	}
+	return files
+}
+
+func (c *certReloader) changed() bool {
+	for _, file := range c.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(c.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *certReloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range c.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	var pool *x509.CertPool
+	if c.config.ClientCAFile != "" {
+		data, err := os.ReadFile(c.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("tls: no certificates in %s", c.config.ClientCAFile)
+		}
+	}
+
+	c.cert, c.clientCAs, c.modTimes = &cert, pool, modTimes
+	return nil
+}
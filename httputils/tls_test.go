@@ -0,0 +1,126 @@
+package httputils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server-1", ca)
+	client := newTestCert(t, "client", ca)
+
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	modTime := time.Now().Add(-time.Minute)
+	writeFile(t, certFile, server.certPEM, modTime)
+	writeFile(t, keyFile, server.keyPEM, modTime)
+	writeFile(t, caFile, ca.certPEM, modTime)
+
+	r := NewChiRouter("0").
+		SetTLS(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ReloadInterval: time.Nanosecond}).
+		AddPath("/", "GET", func(c Context) error {
+			return c.String(http.StatusOK, c.Request().TLS.PeerCertificates[0].Subject.CommonName)
+		})
+	addr, stop := start(t, r)
+	defer stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCert, _ := tls.X509KeyPair(client.certPEM, client.keyPEM)
+	get := func(config *tls.Config) (string, error) {
+		config.ServerName = "localhost"
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := httpClient.Get("https://" + addr + "/")
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+	}
+
+	if _, err := get(&tls.Config{RootCAs: roots}); err == nil {
+		t.Errorf("request without a client certificate succeeded")
+	}
+	name, err := get(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}})
+	if err != nil || name != "server-1" {
+		t.Fatalf("server certificate = %q, %v, want server-1", name, err)
+	}
+
+	renewed := newTestCert(t, "server-2", ca)
+	writeFile(t, certFile, renewed.certPEM, time.Now())
+	writeFile(t, keyFile, renewed.keyPEM, time.Now())
+	name, err = get(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}})
+	if err != nil || name != "server-2" {
+		t.Errorf("reloaded certificate = %q, %v, want server-2", name, err)
+	}
+}
+
+func TestSetTLSMissingFiles(t *testing.T) {
+	r := NewChiRouter("0").SetTLS(TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"})
+	if err := r.Run(context.Background()); err == nil {
+		t.Errorf("Run() error = nil, want missing certificate error")
+	}
+}
@@ -0,0 +1,373 @@
+package httputils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+
+	defaultWebSocketReadLimit  = 1 << 20
+	defaultWebSocketPongWait   = 60 * time.Second
+	defaultWebSocketWriteWait  = 10 * time.Second
+	defaultWebSocketSendBuffer = 16
+)
+
+// ErrWebSocketClosed is returned when sending on a closed connection.
+var ErrWebSocketClosed = errors.New("httputils: websocket closed")
+
+// WebSocketConfig configures a WebSocket endpoint.
+type WebSocketConfig struct {
+	ReadLimit int64 // max message size in bytes, defaults to 1 MiB
+	// PongWait drops a connection without a pong for this long, defaults to
+	// 60s. Pongs are only read while the handler calls Receive.
+	PongWait   time.Duration
+	WriteWait  time.Duration // defaults to 10s
+	SendBuffer int           // queued outgoing messages; a client that falls further behind is dropped
+	// CheckOrigin defaults to same-origin requests and the origins listed
+	// in the router's CORS AllowOrigins. A "*" there does not apply:
+	// browsers send cookies with the handshake, so any site could open a
+	// connection as the user.
+	CheckOrigin       func(r *http.Request) bool
+	Subprotocols      []string
+	EnableCompression bool
+}
+
+// WebSocketHandler serves one connection; the connection is closed when it
+// returns. A returned error is logged and the client gets a 1011 (internal
+// error) close frame, since the response has already been taken over.
+type WebSocketHandler func(conn *WebSocketConn) error
+
+// WebSocketConn is an upgraded connection. Reads happen on the handler's
+// goroutine through Receive; Send queues messages for a writer goroutine that
+// also sends pings every 9/10 of PongWait.
+//
+// Pongs and the client's close are processed inside Receive, so a handler
+// must keep calling it, even when it only sends, for PongWait and
+// disconnects to take effect:
+//
+//	for {
+//		if _, _, err := conn.Receive(); err != nil {
+//			return nil
+//		}
+//	}
+type WebSocketConn struct {
+	conn    *websocket.Conn
+	request *http.Request
+	params  func(name string) string
+	send    chan wsMessage
+	ctx     context.Context
+	cancel  context.CancelFunc
+	config  WebSocketConfig
+
+	mu        sync.Mutex
+	closed    bool
+	closeCode int
+	onClose   []func()
+}
+
+type wsMessage struct {
+	kind int
+	data []byte
+}
+
+// webSocket returns the HandlerFunc that upgrades requests for handler.
+func (o *options) webSocket(handler WebSocketHandler, configs []WebSocketConfig) HandlerFunc {
+	var config WebSocketConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.ReadLimit <= 0 {
+		config.ReadLimit = defaultWebSocketReadLimit
+	}
+	if config.PongWait <= 0 {
+		config.PongWait = defaultWebSocketPongWait
+	}
+	if config.WriteWait <= 0 {
+		config.WriteWait = defaultWebSocketWriteWait
+	}
+	if config.SendBuffer <= 0 {
+		config.SendBuffer = defaultWebSocketSendBuffer
+	}
+	checkOrigin := config.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = o.checkOrigin
+	}
+	upgrader := websocket.Upgrader{
+		CheckOrigin:       checkOrigin,
+		Subprotocols:      config.Subprotocols,
+		EnableCompression: config.EnableCompression,
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			p := newStatusProblem(r, status)
+			p.Detail = reason.Error()
+			p.Write(w)
+		},
+	}
+
+	return func(c Context) error {
+		ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+		if err != nil {
+			return nil // the upgrader has written the error response
+		}
+		conn := newWebSocketConn(ws, c.Request(), config)
+		conn.params = c.Param
+		defer conn.Close()
+		go conn.writeLoop()
+		if err := handler(conn); err != nil {
+			log.Printf("websocket %s: %v", c.Request().URL.Path, err)
+			conn.closeWith(websocket.CloseInternalServerErr)
+		}
+		return nil
+	}
+}
+
+// checkOrigin allows requests without an Origin, such as non-browser
+// clients, same-origin requests and the origins CORS lists explicitly.
+func (o *options) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if o.cors != nil {
+		for _, pattern := range o.cors.AllowOrigins {
+			if pattern != "*" && matchOrigin(pattern, origin) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newWebSocketConn(ws *websocket.Conn, r *http.Request, config WebSocketConfig) *WebSocketConn {
+	ctx, cancel := context.WithCancel(r.Context())
+	c := &WebSocketConn{
+		conn:      ws,
+		request:   r,
+		send:      make(chan wsMessage, config.SendBuffer),
+		ctx:       ctx,
+		cancel:    cancel,
+		config:    config,
+		closeCode: websocket.CloseNormalClosure,
+	}
+	ws.SetReadLimit(config.ReadLimit)
+	ws.SetReadDeadline(time.Now().Add(config.PongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(config.PongWait))
+	})
+	return c
+}
+
+func (c *WebSocketConn) Request() *http.Request {
+	return c.request
+}
+
+// Param returns a path parameter of the upgrade request.
+func (c *WebSocketConn) Param(name string) string {
+	return c.params(name)
+}
+
+// Context is done once the connection is closed.
+func (c *WebSocketConn) Context() context.Context {
+	return c.ctx
+}
+
+// Receive reads the next message. It fails once the client closes the
+// connection, sends a message over ReadLimit or stops answering pings.
+func (c *WebSocketConn) Receive() (messageType int, data []byte, err error) {
+	messageType, data, err = c.conn.ReadMessage()
+	if err != nil {
+		c.Close()
+	}
+	return messageType, data, err
+}
+
+// ReceiveJSON reads the next message into v.
+func (c *WebSocketConn) ReceiveJSON(v interface{}) error {
+	_, data, err := c.Receive()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Send queues a message. A client whose queue is full is too slow to keep
+// up and is disconnected.
+func (c *WebSocketConn) Send(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrWebSocketClosed
+	}
+	select {
+	case c.send <- wsMessage{kind: messageType, data: data}:
+		return nil
+	default:
+		c.closeLocked()
+		return ErrWebSocketClosed
+	}
+}
+
+func (c *WebSocketConn) SendText(s string) error {
+	return c.Send(TextMessage, []byte(s))
+}
+
+func (c *WebSocketConn) SendJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(TextMessage, data)
+}
+
+// Close stops the connection after the queued messages are written, and
+// removes it from every Hub room it joined.
+func (c *WebSocketConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked()
+	return nil
+}
+
+// closeWith closes the connection with the given close frame status code.
+func (c *WebSocketConn) closeWith(code int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closeCode = code
+	}
+	c.closeLocked()
+}
+
+func (c *WebSocketConn) closeLocked() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
+	for _, f := range c.onClose {
+		go f()
+	}
+}
+
+// addOnClose runs f when the connection closes, right away if it already has.
+func (c *WebSocketConn) addOnClose(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		go f()
+		return
+	}
+	c.onClose = append(c.onClose, f)
+}
+
+func (c *WebSocketConn) writeLoop() {
+	ticker := time.NewTicker(c.config.PongWait * 9 / 10)
+	defer func() {
+		ticker.Stop()
+		c.cancel()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(msg.kind, msg.data); err != nil {
+				c.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+// Hub groups connections into rooms for broadcasting. Connections leave
+// their rooms when they close.
+type Hub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[*WebSocketConn]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]map[*WebSocketConn]struct{})}
+}
+
+func (h *Hub) Join(room string, conn *WebSocketConn) {
+	h.mu.Lock()
+	members, ok := h.rooms[room]
+	if !ok {
+		members = make(map[*WebSocketConn]struct{})
+		h.rooms[room] = members
+	}
+	_, joined := members[conn]
+	members[conn] = struct{}{}
+	h.mu.Unlock()
+
+	if !joined {
+		conn.addOnClose(func() { h.Leave(room, conn) })
+	}
+}
+
+func (h *Hub) Leave(room string, conn *WebSocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if members, ok := h.rooms[room]; ok {
+		delete(members, conn)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Count returns the number of connections in room.
+func (h *Hub) Count(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast sends a message to every connection in room.
+func (h *Hub) Broadcast(room string, messageType int, data []byte) {
+	h.mu.RLock()
+	members := make([]*WebSocketConn, 0, len(h.rooms[room]))
+	for conn := range h.rooms[room] {
+		members = append(members, conn)
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range members {
+		conn.Send(messageType, data)
+	}
+}
+
+func (h *Hub) BroadcastJSON(room string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(room, TextMessage, data)
+	return nil
+}
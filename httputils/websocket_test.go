@@ -0,0 +1,144 @@
+package httputils
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			hub := NewHub()
+			r := newRouter("0").
+				AllowCors(CorsConfig{AllowOrigins: []string{"https://app.example.com"}}).
+				AddWebSocket("/rooms/{room}", func(conn *WebSocketConn) error {
+					room := conn.Param("room")
+					hub.Join(room, conn)
+					for {
+						_, data, err := conn.Receive()
+						if err != nil {
+							return nil
+						}
+						hub.Broadcast(room, TextMessage, data)
+					}
+				}, WebSocketConfig{ReadLimit: 16})
+			addr, stop := start(t, r)
+			defer stop()
+
+			dial := func(origin string) (*websocket.Conn, int) {
+				header := http.Header{}
+				if origin != "" {
+					header.Set("Origin", origin)
+				}
+				conn, resp, err := websocket.DefaultDialer.Dial("ws://"+addr+"/rooms/chat", header)
+				if err != nil {
+					if resp == nil {
+						t.Fatal(err)
+					}
+					return nil, resp.StatusCode
+				}
+				return conn, resp.StatusCode
+			}
+
+			if _, status := dial("https://evil.example.com"); status != http.StatusForbidden {
+				t.Errorf("disallowed origin status = %d, want 403", status)
+			}
+			alice, status := dial("https://app.example.com")
+			if alice == nil {
+				t.Fatalf("allowed origin status = %d", status)
+			}
+			defer alice.Close()
+			bob, _ := dial("")
+			if bob == nil {
+				t.Fatal("dial without origin failed")
+			}
+			defer bob.Close()
+			waitFor(t, func() bool { return hub.Count("chat") == 2 })
+
+			if err := alice.WriteMessage(websocket.TextMessage, []byte("hi")); err != nil {
+				t.Fatal(err)
+			}
+			for _, conn := range []*websocket.Conn{alice, bob} {
+				conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+				if _, data, err := conn.ReadMessage(); err != nil || string(data) != "hi" {
+					t.Errorf("broadcast = %q, %v, want hi", data, err)
+				}
+			}
+
+			// over ReadLimit: the server drops bob and he leaves the room
+			bob.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 32)))
+			bob.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, _, err := bob.ReadMessage(); err == nil {
+				t.Errorf("oversized message did not close the connection")
+			}
+			waitFor(t, func() bool { return hub.Count("chat") == 1 })
+		})
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func TestWebSocketCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name   string
+		cors   *CorsConfig
+		origin string
+		want   bool
+	}{
+		{name: "no origin", origin: "", want: true},
+		{name: "same origin", origin: "http://api.example.com", want: true},
+		{name: "other origin", origin: "https://evil.example.com", want: false},
+		{name: "listed origin", cors: &CorsConfig{AllowOrigins: []string{"https://*.example.com"}}, origin: "https://app.example.com", want: true},
+		{name: "any origin", cors: &DefaultCorsConfig, origin: "https://evil.example.com", want: false},
+		{name: "any origin, same origin", cors: &DefaultCorsConfig, origin: "http://api.example.com", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &options{cors: tt.cors}
+			r, _ := http.NewRequest(http.MethodGet, "http://api.example.com/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := o.checkOrigin(r); got != tt.want {
+				t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketHandlerError(t *testing.T) {
+	for name, newRouter := range backends() {
+		t.Run(name, func(t *testing.T) {
+			r := newRouter("0").AddWebSocket("/ws", func(conn *WebSocketConn) error {
+				return errors.New("boom")
+			})
+			addr, stop := start(t, r)
+			defer stop()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, _, err = conn.ReadMessage()
+			if !websocket.IsCloseError(err, websocket.CloseInternalServerErr) {
+				t.Errorf("ReadMessage() error = %v, want close 1011", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"runtime"
@@ -18,7 +19,9 @@ type Logger interface {
 	Fatal(msg string, keyvals ...interface{})
 	SetCaller() Logger
 	SetLevel(level int) Logger
+	Level() int
 	LogFile(path string) Logger
+	WithContext(ctx context.Context) Logger
 }
 
 var logObj Logger
@@ -0,0 +1,44 @@
+package logger
+
+import "context"
+
+type contextKey string
+
+const (
+	requestIDKey     contextKey = "request_id"
+	correlationIDKey contextKey = "correlation_id"
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithCorrelationID returns a copy of ctx carrying the correlation ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, id)
+}
+
+// CorrelationID returns the correlation ID stored in ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey).(string)
+	return id
+}
+
+// contextFields returns the IDs in ctx as log fields.
+func contextFields(ctx context.Context) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if id := RequestID(ctx); id != "" {
+		fields[string(requestIDKey)] = id
+	}
+	if id := CorrelationID(ctx); id != "" {
+		fields[string(correlationIDKey)] = id
+	}
+	return fields
+}
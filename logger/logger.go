@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +17,7 @@ type logrusLogger struct {
 	level     int
 	file      bool
 	sentryUrl string
+	fields    logrus.Fields
 }
 
 func NewLogger() Logger {
@@ -64,11 +66,40 @@ func (l *logrusLogger) SetLevel(level int) Logger {
 	return l
 }
 
+func (l *logrusLogger) Level() int {
+	return int(l.log.GetLevel())
+}
+
+// ParseLevel returns the level named s, such as "debug" or "info".
+func ParseLevel(s string) (int, error) {
+	level, err := logrus.ParseLevel(s)
+	return int(level), err
+}
+
+// LevelName returns the name of level.
+func LevelName(level int) string {
+	return logrus.Level(level).String()
+}
+
 func (l *logrusLogger) SetCaller() Logger {
 	l.caller = true
 	return l
 }
 
+// WithContext returns a logger that adds the request and correlation IDs in ctx to every line.
+func (l *logrusLogger) WithContext(ctx context.Context) Logger {
+	fields := make(logrus.Fields, len(l.fields))
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	for k, v := range contextFields(ctx) {
+		fields[k] = v
+	}
+	c := *l
+	c.fields = fields
+	return &c
+}
+
 func (l *logrusLogger) Debug(msg string, keyvals ...interface{}) {
 	l.log.WithFields(l.append(keyvals...)).Debug(msg)
 }
@@ -91,6 +122,9 @@ func (l *logrusLogger) Fatal(msg string, keyvals ...interface{}) {
 
 func (l *logrusLogger) append(keyvals ...interface{}) logrus.Fields {
 	fields := make(logrus.Fields)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
 	if l.caller {
 		fields["caller"] = caller(3)
 	}
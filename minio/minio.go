@@ -2,6 +2,7 @@ package minio
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 
@@ -100,6 +101,22 @@ func (s *service) CreateBucket(name string) error {
 	return nil
 }
 
+// Ping lists buckets to check the connection; minio-go v6 has no
+// context support, so ctx only bounds how long Ping waits.
+func (s *service) Ping(ctx context.Context) error {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.client.ListBuckets()
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (s *service) BucketExist(name string) bool {
 	found, err := s.client.BucketExists(name)
 	if err != nil {
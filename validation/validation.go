@@ -0,0 +1,342 @@
+// Package validation checks structs against `validate` tags.
+//
+// Rules are separated by commas:
+//
+//	Name  string   `json:"name" validate:"required,min=2,max=50"`
+//	Email string   `json:"email" validate:"required,email"`
+//	Role  string   `json:"role" validate:"oneof=admin user"`
+//	Code  string   `json:"code" validate:"regex=^[A-Z]{3}$"`
+//	Tags  []string `json:"tags" validate:"max=5"`
+//
+// min and max bound the length of strings, slices and maps and the value of
+// numbers. Rules apply to empty fields too; omitempty skips them when the
+// field is empty:
+//
+//	Phone string `json:"phone" validate:"omitempty,min=9"`
+//
+// regex takes the rest of the tag, so it must be the last rule. Nested
+// structs, and slices of them, are validated too. Tags are parsed once per
+// type; an unknown rule, a bad bound or a bad regex makes Struct return an
+// error instead of errs.FieldErrors.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+var types sync.Map // reflect.Type -> *structRules
+
+// structRules are the parsed validate tags of a struct type.
+type structRules struct {
+	fields []fieldRules
+	err    error
+}
+
+type fieldRules struct {
+	index     int
+	name      string
+	embedded  bool // untagged embedded field, validated under the parent's prefix
+	required  bool
+	omitempty bool
+	rules     []rule
+}
+
+// rule is a Rule with its argument parsed.
+type rule struct {
+	Rule
+	bound   float64
+	options []string
+	re      *regexp.Regexp
+}
+
+// Struct validates v, a struct or a pointer to one. It returns
+// errs.FieldErrors listing every invalid field, or nil.
+func Struct(v interface{}) error {
+	var fields errs.FieldErrors
+	if err := validateStruct(reflect.ValueOf(v), "", &fields); err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return fields
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, fields *errs.FieldErrors) error {
+	v = indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	rules := rulesOf(v.Type())
+	if rules.err != nil {
+		return rules.err
+	}
+	for _, f := range rules.fields {
+		fv := v.Field(f.index)
+		if f.embedded {
+			if err := validateStruct(fv, prefix, fields); err != nil {
+				return err
+			}
+			continue
+		}
+		name := prefix + f.name
+		if msg := f.check(fv); msg != "" {
+			*fields = append(*fields, errs.FieldError{Field: name, Message: msg})
+			continue
+		}
+
+		switch iv := indirect(fv); iv.Kind() {
+		case reflect.Struct:
+			if err := validateStruct(iv, name+".", fields); err != nil {
+				return err
+			}
+		case reflect.Slice, reflect.Array:
+			for j := 0; j < iv.Len(); j++ {
+				if err := validateStruct(iv.Index(j), fmt.Sprintf("%s[%d].", name, j), fields); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rulesOf returns the parsed rules of t, parsing them on first use.
+func rulesOf(t reflect.Type) *structRules {
+	if rules, ok := types.Load(t); ok {
+		return rules.(*structRules)
+	}
+	rules := parseStruct(t)
+	actual, _ := types.LoadOrStore(t, rules)
+	return actual.(*structRules)
+}
+
+func parseStruct(t reflect.Type) *structRules {
+	rules := &structRules{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		field := fieldRules{index: i, name: FieldName(f), embedded: f.Anonymous && tag == ""}
+		for _, r := range ParseTag(tag) {
+			parsed, err := parseRule(r)
+			if err != nil {
+				rules.err = fmt.Errorf("validation: %s.%s: %v", t, f.Name, err)
+				return rules
+			}
+			switch r.Name {
+			case "required":
+				field.required = true
+			case "omitempty":
+				field.omitempty = true
+			default:
+				field.rules = append(field.rules, parsed)
+			}
+		}
+		rules.fields = append(rules.fields, field)
+	}
+	return rules
+}
+
+func parseRule(r Rule) (rule, error) {
+	parsed := rule{Rule: r}
+	switch r.Name {
+	case "required", "omitempty", "email":
+	case "min", "max":
+		bound, err := strconv.ParseFloat(r.Arg, 64)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid bound %s", strconv.Quote(r.Arg))
+		}
+		parsed.bound = bound
+	case "oneof":
+		parsed.options = strings.Fields(r.Arg)
+	case "regex":
+		re, err := regexp.Compile(r.Arg)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid regex %s: %v", strconv.Quote(r.Arg), err)
+		}
+		parsed.re = re
+	default:
+		return parsed, fmt.Errorf("unknown rule %s", strconv.Quote(r.Name))
+	}
+	return parsed, nil
+}
+
+// FieldName is the name a field is reported under: its json, form, query or
+// param tag, or the Go field name.
+func FieldName(f reflect.StructField) string {
+	for _, key := range []string{"json", "form", "query", "param"} {
+		if name := strings.Split(f.Tag.Get(key), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// check returns the message of the first rule v breaks, or "".
+func (f fieldRules) check(v reflect.Value) string {
+	if isEmpty(v) {
+		if f.required {
+			return "is required"
+		}
+		if f.omitempty {
+			return ""
+		}
+	}
+
+	v = value(v)
+	for _, rule := range f.rules {
+		var msg string
+		switch rule.Name {
+		case "min":
+			msg = checkBound(v, rule, true)
+		case "max":
+			msg = checkBound(v, rule, false)
+		case "email":
+			msg = checkEmail(v)
+		case "oneof":
+			msg = checkOneOf(v, rule.options)
+		case "regex":
+			msg = checkRegex(v, rule)
+		}
+		if msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// Rule is one rule of a validate tag, such as min=2.
+type Rule struct {
+	Name string
+	Arg  string
+}
+
+// ParseTag splits a validate tag into its rules.
+func ParseTag(tag string) []Rule {
+	var rules []Rule
+	for tag != "" {
+		rule := tag
+		if strings.HasPrefix(tag, "regex=") {
+			tag = ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			rule, tag = tag[:i], tag[i+1:]
+		} else {
+			tag = ""
+		}
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		name, arg := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		rules = append(rules, Rule{Name: name, Arg: arg})
+	}
+	return rules
+}
+
+func checkBound(v reflect.Value, r rule, min bool) string {
+	word := "most"
+	if min {
+		word = "least"
+	}
+
+	var n float64
+	var unit string
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		n, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	default:
+		return ""
+	}
+	if (min && n < r.bound) || (!min && n > r.bound) {
+		if unit == "" {
+			return fmt.Sprintf("must be at %s %s", word, r.Arg)
+		}
+		return fmt.Sprintf("must have at %s %s%s", word, r.Arg, unit)
+	}
+	return ""
+}
+
+func checkEmail(v reflect.Value) string {
+	if v.Kind() != reflect.String {
+		return ""
+	}
+	addr, err := mail.ParseAddress(v.String())
+	if err != nil || addr.Address != v.String() {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+func checkOneOf(v reflect.Value, options []string) string {
+	if v.IsValid() {
+		s := fmt.Sprint(v.Interface())
+		for _, o := range options {
+			if s == o {
+				return ""
+			}
+		}
+	}
+	return "must be one of " + strings.Join(options, ", ")
+}
+
+func checkRegex(v reflect.Value, r rule) string {
+	if v.Kind() != reflect.String {
+		return ""
+	}
+	if !r.re.MatchString(v.String()) {
+		return "must match " + r.Arg
+	}
+	return ""
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// value is v with pointers followed; a nil pointer stands for the zero value
+// of the type it points to, and a nil interface for no value at all.
+func value(v reflect.Value) reflect.Value {
+	v = indirect(v)
+	for v.Kind() == reflect.Ptr {
+		v = reflect.Zero(v.Type().Elem())
+	}
+	if v.Kind() == reflect.Interface {
+		return reflect.Value{}
+	}
+	return v
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
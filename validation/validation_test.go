@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/huylqbk/codesample/errs"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type user struct {
+	Name    string    `json:"name" validate:"required,min=2,max=5"`
+	Email   string    `json:"email" validate:"email"`
+	Role    string    `json:"role" validate:"oneof=admin user"`
+	Code    string    `json:"code" validate:"regex=^[A-Z]{2,3}$"`
+	Age     int       `json:"age" validate:"min=18"`
+	Tags    []string  `json:"tags" validate:"max=2"`
+	Address *address  `json:"address" validate:"required"`
+	Others  []address `json:"others"`
+}
+
+func TestStruct(t *testing.T) {
+	valid := user{Name: "ann", Email: "ann@example.com", Role: "admin", Code: "AB", Age: 20, Address: &address{City: "Hanoi"}}
+	if err := Struct(&valid); err != nil {
+		t.Fatalf("Struct(valid) = %v", err)
+	}
+
+	invalid := user{
+		Name:   "annabel",
+		Email:  "Ann <ann@example.com>",
+		Role:   "root",
+		Code:   "abc",
+		Age:    17,
+		Tags:   []string{"a", "b", "c"},
+		Others: []address{{City: "Hue"}, {}},
+	}
+	err := Struct(invalid)
+	fields, ok := err.(errs.FieldErrors)
+	if !ok {
+		t.Fatalf("Struct(invalid) = %v, want errs.FieldErrors", err)
+	}
+	var got []string
+	for _, f := range fields {
+		got = append(got, f.Field)
+	}
+	want := []string{"name", "email", "role", "code", "age", "tags", "address", "others[1].city"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("invalid fields = %v, want %v", got, want)
+	}
+	if errs.ToCode(err) != 400 {
+		t.Errorf("ToCode() = %d, want 400", errs.ToCode(err))
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	got := ParseTag("required, min=1,regex=^(a|b),c$")
+	want := []Rule{{Name: "required"}, {Name: "min", Arg: "1"}, {Name: "regex", Arg: "^(a|b),c$"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTag() = %v, want %v", got, want)
+	}
+}
+
+func TestStructEmptyValues(t *testing.T) {
+	type input struct {
+		Count int     `json:"count" validate:"min=1"`
+		Role  string  `json:"role" validate:"oneof=admin user"`
+		Limit *int    `json:"limit" validate:"min=1"`
+		Phone string  `json:"phone" validate:"omitempty,min=9"`
+		Email *string `json:"email" validate:"omitempty,email"`
+	}
+	err := Struct(input{})
+	fields, ok := err.(errs.FieldErrors)
+	if !ok {
+		t.Fatalf("Struct() = %v, want errs.FieldErrors", err)
+	}
+	var got []string
+	for _, f := range fields {
+		got = append(got, f.Field+" "+f.Message)
+	}
+	want := []string{"count must be at least 1", "role must be one of admin, user", "limit must be at least 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Struct() = %v, want %v", got, want)
+	}
+}
+
+func TestStructInvalidTag(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{name: "unknown rule", v: struct {
+			Name string `validate:"requird"`
+		}{}},
+		{name: "bad bound", v: struct {
+			Name string `validate:"min=two"`
+		}{}},
+		{name: "bad regex", v: struct {
+			Name string `validate:"regex=^(a"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				err := Struct(tt.v)
+				if _, ok := err.(errs.FieldErrors); err == nil || ok {
+					t.Errorf("Struct() = %v, want a tag error", err)
+				}
+			}
+		})
+	}
+}